@@ -4,6 +4,7 @@ import (
 	"fmt"
 	"os"
 	"strconv"
+	"strings"
 
 	"github.com/joho/godotenv"
 )
@@ -27,11 +28,37 @@ type Config struct {
 	DataDir         string
 	TypingDelay     int
 	AutoReadReceipt bool
+	OptOutKeywords  string
 
 	// Webhook
-	WebhookURL     string
-	WebhookSecret  string
-	WebhookTimeout int
+	WebhookURL         string
+	WebhookSecret      string
+	WebhookSecrets     string
+	WebhookFormat      string
+	WebhookSource      string
+	WebhookTimeout     int
+	WebhookMaxAttempts int
+	WebhookRetryBase   int
+	WebhookRetryMax    int
+	WebhookOrdering    string
+	WebhookConcurrency int
+	WebhookBatchSize   int
+	WebhookBatchWindow int
+	WebhookLogHours    int
+
+	// Event sinks
+	EventSinks   string
+	NATSURL      string
+	NATSSubject  string
+	NATSSubjects string
+
+	// JWT bearer tokens
+	JWTJWKSFile    string
+	JWTIssuer      string
+	JWTAudience    string
+	JWTScopeClaim  string
+	JWTTokensClaim string
+	JWTTenantClaim string
 
 	// Rate limiting
 	RateLimitDevices  int
@@ -50,26 +77,48 @@ func Load() (*Config, error) {
 	_ = godotenv.Load()
 
 	cfg := &Config{
-		Port:              getEnvInt("PORT", 4010),
-		Host:              getEnv("HOST", "0.0.0.0"),
-		APIKey:            getEnv("API_KEY", ""),
-		LogLevel:          getEnv("LOG_LEVEL", "info"),
-		CORSOrigins:       getEnv("CORS_ORIGINS", "*"),
-		PhoneCountryCode:  getEnv("PHONE_COUNTRY_CODE", "60"),
-		PhoneMinLength:    getEnvInt("PHONE_MIN_LENGTH", 11),
-		PhoneMaxLength:    getEnvInt("PHONE_MAX_LENGTH", 12),
-		DataDir:           getEnv("DATA_DIR", "./data"),
-		TypingDelay:       getEnvInt("TYPING_DELAY_MS", 1000),
-		AutoReadReceipt:   getEnvBool("AUTO_READ_RECEIPT", false),
-		WebhookURL:        getEnv("WEBHOOK_URL", ""),
-		WebhookSecret:     getEnv("WEBHOOK_SECRET", ""),
-		WebhookTimeout:    getEnvInt("WEBHOOK_TIMEOUT_MS", 5000),
-		RateLimitDevices:  getEnvInt("RATE_LIMIT_DEVICES", 10),
-		RateLimitMessages: getEnvInt("RATE_LIMIT_MESSAGES", 30),
-		RateLimitValidate: getEnvInt("RATE_LIMIT_VALIDATE", 60),
-		CacheTTL:          getEnvInt("CACHE_TTL_SECONDS", 3600),
-		WSAllowedOrigins:  getEnv("WS_ALLOWED_ORIGINS", "*"),
-		WSAuthTimeout:     getEnvInt("WS_AUTH_TIMEOUT", 5),
+		Port:               getEnvInt("PORT", 4010),
+		Host:               getEnv("HOST", "0.0.0.0"),
+		APIKey:             getEnv("API_KEY", ""),
+		LogLevel:           getEnv("LOG_LEVEL", "info"),
+		CORSOrigins:        getEnv("CORS_ORIGINS", "*"),
+		PhoneCountryCode:   getEnv("PHONE_COUNTRY_CODE", "60"),
+		PhoneMinLength:     getEnvInt("PHONE_MIN_LENGTH", 11),
+		PhoneMaxLength:     getEnvInt("PHONE_MAX_LENGTH", 12),
+		DataDir:            getEnv("DATA_DIR", "./data"),
+		TypingDelay:        getEnvInt("TYPING_DELAY_MS", 1000),
+		AutoReadReceipt:    getEnvBool("AUTO_READ_RECEIPT", false),
+		OptOutKeywords:     getEnv("OPT_OUT_KEYWORDS", "STOP,UNSUBSCRIBE"),
+		WebhookURL:         getEnv("WEBHOOK_URL", ""),
+		WebhookSecret:      getEnv("WEBHOOK_SECRET", ""),
+		WebhookSecrets:     getEnv("WEBHOOK_SECRETS", ""),
+		WebhookFormat:      getEnv("WEBHOOK_FORMAT", "json"),
+		WebhookSource:      getEnv("WEBHOOK_SOURCE", "wa-gateway-go"),
+		WebhookTimeout:     getEnvInt("WEBHOOK_TIMEOUT_MS", 5000),
+		WebhookMaxAttempts: getEnvInt("WEBHOOK_MAX_ATTEMPTS", 8),
+		WebhookRetryBase:   getEnvInt("WEBHOOK_RETRY_BASE_MS", 1000),
+		WebhookRetryMax:    getEnvInt("WEBHOOK_RETRY_MAX_MS", 600000),
+		WebhookOrdering:    getEnv("WEBHOOK_ORDERING", "none"),
+		WebhookConcurrency: getEnvInt("WEBHOOK_CONCURRENCY", 16),
+		WebhookBatchSize:   getEnvInt("WEBHOOK_BATCH_SIZE", 1),
+		WebhookBatchWindow: getEnvInt("WEBHOOK_BATCH_WINDOW_MS", 1000),
+		WebhookLogHours:    getEnvInt("WEBHOOK_LOG_RETENTION_HOURS", 168),
+		EventSinks:         getEnv("EVENT_SINKS", "http"),
+		NATSURL:            getEnv("NATS_URL", "nats://127.0.0.1:4222"),
+		NATSSubject:        getEnv("NATS_SUBJECT", "wa.{event}.{token}"),
+		NATSSubjects:       getEnv("NATS_SUBJECTS", ""),
+		JWTJWKSFile:        getEnv("JWT_JWKS_FILE", ""),
+		JWTIssuer:          getEnv("JWT_ISSUER", ""),
+		JWTAudience:        getEnv("JWT_AUDIENCE", ""),
+		JWTScopeClaim:      getEnv("JWT_SCOPE_CLAIM", "scope"),
+		JWTTokensClaim:     getEnv("JWT_TOKENS_CLAIM", "devices"),
+		JWTTenantClaim:     getEnv("JWT_TENANT_CLAIM", "tenant"),
+		RateLimitDevices:   getEnvInt("RATE_LIMIT_DEVICES", 10),
+		RateLimitMessages:  getEnvInt("RATE_LIMIT_MESSAGES", 30),
+		RateLimitValidate:  getEnvInt("RATE_LIMIT_VALIDATE", 60),
+		CacheTTL:           getEnvInt("CACHE_TTL_SECONDS", 3600),
+		WSAllowedOrigins:   getEnv("WS_ALLOWED_ORIGINS", "*"),
+		WSAuthTimeout:      getEnvInt("WS_AUTH_TIMEOUT", 5),
 	}
 
 	if err := cfg.validate(); err != nil {
@@ -86,12 +135,75 @@ func (c *Config) validate() error {
 	if c.Port < 1 || c.Port > 65535 {
 		return fmt.Errorf("PORT must be between 1 and 65535")
 	}
+	if c.WebhookMaxAttempts < 1 {
+		return fmt.Errorf("WEBHOOK_MAX_ATTEMPTS must be at least 1")
+	}
+	switch c.WebhookFormat {
+	case "json", "cloudevents", "cloudevents-binary":
+	default:
+		return fmt.Errorf("WEBHOOK_FORMAT must be json, cloudevents or cloudevents-binary")
+	}
+	switch c.WebhookOrdering {
+	case "none", "device", "chat":
+	default:
+		return fmt.Errorf("WEBHOOK_ORDERING must be none, device or chat")
+	}
+	if c.WebhookConcurrency < 1 {
+		return fmt.Errorf("WEBHOOK_CONCURRENCY must be at least 1")
+	}
+	if c.WebhookBatchSize < 1 {
+		return fmt.Errorf("WEBHOOK_BATCH_SIZE must be at least 1")
+	}
+	if c.WebhookBatchWindow < 0 {
+		return fmt.Errorf("WEBHOOK_BATCH_WINDOW_MS must not be negative")
+	}
+	if c.WebhookLogHours < 0 {
+		return fmt.Errorf("WEBHOOK_LOG_RETENTION_HOURS must not be negative")
+	}
+	sinks := c.Sinks()
+	if len(sinks) == 0 {
+		return fmt.Errorf("EVENT_SINKS must list at least one sink")
+	}
+	for _, s := range sinks {
+		if s != "http" && s != "nats" {
+			return fmt.Errorf("EVENT_SINKS: unknown sink %q (expected http or nats)", s)
+		}
+	}
+	if c.JWTAudience != "" && !c.JWTEnabled() {
+		return fmt.Errorf("JWT_AUDIENCE requires JWT_JWKS_FILE or JWT_ISSUER")
+	}
 	if c.WSAuthTimeout < 1 || c.WSAuthTimeout > 60 {
 		return fmt.Errorf("WS_AUTH_TIMEOUT must be between 1 and 60")
 	}
 	return nil
 }
 
+// Sinks returns the event sinks listed in EVENT_SINKS.
+func (c *Config) Sinks() []string {
+	var sinks []string
+	for _, s := range strings.Split(c.EventSinks, ",") {
+		if s = strings.TrimSpace(strings.ToLower(s)); s != "" {
+			sinks = append(sinks, s)
+		}
+	}
+	return sinks
+}
+
+// JWTEnabled reports whether JWT bearer tokens are accepted.
+func (c *Config) JWTEnabled() bool {
+	return c.JWTJWKSFile != "" || c.JWTIssuer != ""
+}
+
+// HasSink reports whether EVENT_SINKS includes name.
+func (c *Config) HasSink(name string) bool {
+	for _, s := range c.Sinks() {
+		if s == name {
+			return true
+		}
+	}
+	return false
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
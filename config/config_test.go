@@ -9,8 +9,15 @@ func clearConfigEnv() {
 	envVars := []string{
 		"API_KEY", "PORT", "HOST", "LOG_LEVEL", "CORS_ORIGINS",
 		"PHONE_COUNTRY_CODE", "PHONE_MIN_LENGTH", "PHONE_MAX_LENGTH",
-		"DATA_DIR", "TYPING_DELAY_MS", "AUTO_READ_RECEIPT",
-		"WEBHOOK_URL", "WEBHOOK_SECRET", "WEBHOOK_TIMEOUT_MS",
+		"DATA_DIR", "TYPING_DELAY_MS", "AUTO_READ_RECEIPT", "OPT_OUT_KEYWORDS",
+		"WEBHOOK_URL", "WEBHOOK_SECRET", "WEBHOOK_SECRETS", "WEBHOOK_TIMEOUT_MS",
+		"WEBHOOK_FORMAT", "WEBHOOK_SOURCE",
+		"WEBHOOK_MAX_ATTEMPTS", "WEBHOOK_RETRY_BASE_MS", "WEBHOOK_RETRY_MAX_MS",
+		"WEBHOOK_ORDERING", "WEBHOOK_CONCURRENCY", "WEBHOOK_LOG_RETENTION_HOURS",
+		"WEBHOOK_BATCH_SIZE", "WEBHOOK_BATCH_WINDOW_MS",
+		"EVENT_SINKS", "NATS_URL", "NATS_SUBJECT", "NATS_SUBJECTS",
+		"JWT_JWKS_FILE", "JWT_ISSUER", "JWT_AUDIENCE",
+		"JWT_SCOPE_CLAIM", "JWT_TOKENS_CLAIM", "JWT_TENANT_CLAIM",
 		"RATE_LIMIT_DEVICES", "RATE_LIMIT_MESSAGES", "RATE_LIMIT_VALIDATE",
 		"CACHE_TTL_SECONDS",
 		"WS_ALLOWED_ORIGINS", "WS_AUTH_TIMEOUT",
@@ -80,6 +87,9 @@ func TestLoad_Defaults(t *testing.T) {
 	if cfg.WebhookSecret != "" {
 		t.Errorf("expected WebhookSecret '', got %q", cfg.WebhookSecret)
 	}
+	if cfg.WebhookSecrets != "" {
+		t.Errorf("expected WebhookSecrets '', got %q", cfg.WebhookSecrets)
+	}
 	if cfg.WebhookTimeout != 5000 {
 		t.Errorf("expected WebhookTimeout 5000, got %d", cfg.WebhookTimeout)
 	}
@@ -118,6 +128,7 @@ func TestLoad_CustomValues(t *testing.T) {
 	t.Setenv("AUTO_READ_RECEIPT", "true")
 	t.Setenv("WEBHOOK_URL", "https://example.com/webhook")
 	t.Setenv("WEBHOOK_SECRET", "webhook-secret")
+	t.Setenv("WEBHOOK_SECRETS", "new-secret,old-secret")
 	t.Setenv("WEBHOOK_TIMEOUT_MS", "10000")
 	t.Setenv("RATE_LIMIT_DEVICES", "5")
 	t.Setenv("RATE_LIMIT_MESSAGES", "60")
@@ -167,6 +178,9 @@ func TestLoad_CustomValues(t *testing.T) {
 	if cfg.WebhookSecret != "webhook-secret" {
 		t.Errorf("expected WebhookSecret 'webhook-secret', got %q", cfg.WebhookSecret)
 	}
+	if cfg.WebhookSecrets != "new-secret,old-secret" {
+		t.Errorf("expected WebhookSecrets 'new-secret,old-secret', got %q", cfg.WebhookSecrets)
+	}
 	if cfg.WebhookTimeout != 10000 {
 		t.Errorf("expected WebhookTimeout 10000, got %d", cfg.WebhookTimeout)
 	}
@@ -224,6 +238,74 @@ func TestLoad_NegativePort(t *testing.T) {
 	}
 }
 
+func TestLoad_InvalidWebhookFormat(t *testing.T) {
+	clearConfigEnv()
+	t.Setenv("API_KEY", "test-key")
+	t.Setenv("WEBHOOK_FORMAT", "xml")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected error for unknown webhook format")
+	}
+}
+
+func TestLoad_InvalidWebhookOrdering(t *testing.T) {
+	clearConfigEnv()
+	t.Setenv("API_KEY", "test-key")
+	t.Setenv("WEBHOOK_ORDERING", "global")
+
+	_, err := Load()
+	if err == nil {
+		t.Fatal("expected error for unknown webhook ordering")
+	}
+}
+
+func TestLoad_EventSinks(t *testing.T) {
+	clearConfigEnv()
+	t.Setenv("API_KEY", "test-key")
+	t.Setenv("EVENT_SINKS", " NATS, http ")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.HasSink("nats") || !cfg.HasSink("http") {
+		t.Errorf("expected nats and http sinks, got %v", cfg.Sinks())
+	}
+
+	t.Setenv("EVENT_SINKS", "kafka")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for unknown sink")
+	}
+}
+
+func TestLoad_JWT(t *testing.T) {
+	clearConfigEnv()
+	t.Setenv("API_KEY", "test-key")
+
+	cfg, err := Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if cfg.JWTEnabled() || cfg.JWTScopeClaim != "scope" || cfg.JWTTokensClaim != "devices" || cfg.JWTTenantClaim != "tenant" {
+		t.Errorf("unexpected JWT defaults: %+v", cfg)
+	}
+
+	t.Setenv("JWT_AUDIENCE", "wa-gateway")
+	if _, err := Load(); err == nil {
+		t.Fatal("expected error for JWT_AUDIENCE without a key source")
+	}
+
+	t.Setenv("JWT_ISSUER", "https://sso.example.com")
+	cfg, err = Load()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !cfg.JWTEnabled() {
+		t.Error("expected JWT to be enabled with JWT_ISSUER")
+	}
+}
+
 func TestLoad_WSAuthTimeoutZero(t *testing.T) {
 	clearConfigEnv()
 	t.Setenv("API_KEY", "test-key")
@@ -0,0 +1,395 @@
+package apikey
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
+
+	_ "modernc.org/sqlite"
+)
+
+var (
+	ErrNotFound = errors.New("not found")
+
+	// ErrOwned is returned when claiming a device another tenant owns
+	ErrOwned = errors.New("device is owned by another tenant")
+)
+
+var tenantRegex = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,63}$`)
+
+// Scopes grant access to groups of endpoints. ScopeAdmin grants every scope.
+const (
+	ScopeDevicesRead  = "devices:read"
+	ScopeDevicesWrite = "devices:write"
+	ScopeMessagesSend = "messages:send"
+	ScopeContactsRead = "contacts:read"
+	ScopeAdmin        = "admin"
+)
+
+// Scopes lists every valid scope.
+var Scopes = []string{ScopeDevicesRead, ScopeDevicesWrite, ScopeMessagesSend, ScopeContactsRead, ScopeAdmin}
+
+// keyPrefix marks gateway-issued keys so they are easy to spot in logs and
+// secret scanners.
+const keyPrefix = "wag_"
+
+// Key is a named API key. The secret itself is never stored, only its
+// SHA-256 hash; Prefix identifies the key in listings. Empty Tokens allow
+// every device. A key with a Tenant only reaches devices that tenant owns.
+type Key struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Prefix    string   `json:"prefix"`
+	Tenant    string   `json:"tenant,omitempty"`
+	Scopes    []string `json:"scopes"`
+	Tokens    []string `json:"tokens"`
+	CreatedAt string   `json:"createdAt"`
+}
+
+// Master is the key used for requests authenticated with API_KEY.
+var Master = &Key{Name: "master", Scopes: []string{ScopeAdmin}, Tokens: []string{}}
+
+func (k *Key) Validate() error {
+	if k.Name == "" {
+		return fmt.Errorf("name is required")
+	}
+	if len(k.Name) > 100 {
+		return fmt.Errorf("name must be at most 100 characters")
+	}
+	if len(k.Scopes) == 0 {
+		return fmt.Errorf("at least one scope is required")
+	}
+	for _, s := range k.Scopes {
+		if !contains(Scopes, s) {
+			return fmt.Errorf("invalid scope %q", s)
+		}
+	}
+	for _, t := range k.Tokens {
+		if err := validator.ValidateToken(t); err != nil {
+			return fmt.Errorf("invalid token %q: must be 7-15 digits", t)
+		}
+	}
+	if k.Tenant != "" {
+		if err := ValidateTenant(k.Tenant); err != nil {
+			return err
+		}
+		// admin reaches every tenant's devices, keys and webhooks
+		if contains(k.Scopes, ScopeAdmin) {
+			return fmt.Errorf("tenant keys cannot have the %s scope", ScopeAdmin)
+		}
+	}
+	return nil
+}
+
+// ValidateTenant checks a tenant name: lowercase letters, digits, '-' and
+// '_', up to 64 characters.
+func ValidateTenant(tenant string) error {
+	if !tenantRegex.MatchString(tenant) {
+		return fmt.Errorf("invalid tenant %q: use lowercase letters, digits, '-' and '_'", tenant)
+	}
+	return nil
+}
+
+// HasScope reports whether the key grants scope.
+func (k *Key) HasScope(scope string) bool {
+	return contains(k.Scopes, ScopeAdmin) || contains(k.Scopes, scope)
+}
+
+// AllowsToken reports whether the key may act on the device.
+func (k *Key) AllowsToken(token string) bool {
+	return len(k.Tokens) == 0 || contains(k.Tokens, token)
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Principal is an authenticated key together with the device ownership
+// needed to enforce its tenant.
+type Principal struct {
+	*Key
+	owners *Store
+}
+
+// CanUse reports whether the principal may act on, and receive events for,
+// the device.
+func (p *Principal) CanUse(token string) bool {
+	if !p.AllowsToken(token) {
+		return false
+	}
+	if p.Tenant == "" {
+		return true
+	}
+	return p.owners != nil && p.owners.Owner(token) == p.Tenant
+}
+
+// Store persists named API keys and which tenant owns each device.
+type Store struct {
+	db *sql.DB
+
+	// owners caches device_owners, which is read on every request
+	mu     sync.RWMutex
+	owners map[string]string
+}
+
+func NewStore(dbPath string) (*Store, error) {
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS api_keys (
+			id         TEXT PRIMARY KEY,
+			name       TEXT NOT NULL,
+			prefix     TEXT NOT NULL,
+			tenant     TEXT NOT NULL DEFAULT '',
+			hash       TEXT NOT NULL UNIQUE,
+			scopes     TEXT NOT NULL,
+			tokens     TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS device_owners (
+			token      TEXT PRIMARY KEY,
+			tenant     TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	s := &Store{db: db, owners: make(map[string]string)}
+	if err := s.loadOwners(); err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+	return s, nil
+}
+
+func (s *Store) loadOwners() error {
+	rows, err := s.db.Query("SELECT token, tenant FROM device_owners")
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var token, tenant string
+		if err := rows.Scan(&token, &tenant); err != nil {
+			return err
+		}
+		s.owners[token] = tenant
+	}
+	return rows.Err()
+}
+
+// Create generates a secret for the key, stores its hash and returns the
+// secret. The secret cannot be retrieved again.
+func (s *Store) Create(k *Key) (string, error) {
+	b := make([]byte, 24)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	secret := keyPrefix + hex.EncodeToString(b)
+
+	k.ID = uuid.New().String()
+	k.Prefix = secret[:len(keyPrefix)+8]
+	k.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	if k.Tokens == nil {
+		k.Tokens = []string{}
+	}
+
+	scopes, err := json.Marshal(k.Scopes)
+	if err != nil {
+		return "", err
+	}
+	tokens, err := json.Marshal(k.Tokens)
+	if err != nil {
+		return "", err
+	}
+
+	_, err = s.db.Exec(
+		"INSERT INTO api_keys (id, name, prefix, tenant, hash, scopes, tokens, created_at) VALUES (?, ?, ?, ?, ?, ?, ?, ?)",
+		k.ID, k.Name, k.Prefix, k.Tenant, hash(secret), string(scopes), string(tokens), k.CreatedAt,
+	)
+	if err != nil {
+		return "", err
+	}
+	return secret, nil
+}
+
+// Authenticate returns the principal for secret, or ErrNotFound.
+func (s *Store) Authenticate(secret string) (*Principal, error) {
+	k, err := s.get("hash", hash(secret))
+	if err != nil {
+		return nil, err
+	}
+	return s.Principal(k), nil
+}
+
+// Principal returns the principal for a key authenticated elsewhere, such as
+// a JWT, so its tenant is checked against the devices in s. s may be nil.
+func (s *Store) Principal(k *Key) *Principal {
+	return &Principal{Key: k, owners: s}
+}
+
+func (s *Store) Get(id string) (*Key, error) {
+	return s.get("id", id)
+}
+
+func (s *Store) get(column, value string) (*Key, error) {
+	row := s.db.QueryRow("SELECT id, name, prefix, tenant, scopes, tokens, created_at FROM api_keys WHERE "+column+" = ?", value)
+	k, err := scanKey(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return k, err
+}
+
+// List returns all keys in creation order.
+func (s *Store) List() ([]Key, error) {
+	rows, err := s.db.Query("SELECT id, name, prefix, tenant, scopes, tokens, created_at FROM api_keys ORDER BY created_at ASC, id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	keys := []Key{}
+	for rows.Next() {
+		k, err := scanKey(rows)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, *k)
+	}
+	return keys, rows.Err()
+}
+
+// Delete revokes a key.
+func (s *Store) Delete(id string) error {
+	res, err := s.db.Exec("DELETE FROM api_keys WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+// Owner returns the tenant that owns the device, or "" if none does.
+func (s *Store) Owner(token string) string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.owners[token]
+}
+
+// Claim makes tenant the owner of an unowned device. It reports false if the
+// tenant already owned it, and returns ErrOwned if another tenant does.
+func (s *Store) Claim(token, tenant string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	switch s.owners[token] {
+	case tenant:
+		return false, nil
+	case "":
+	default:
+		return false, ErrOwned
+	}
+
+	_, err := s.db.Exec(
+		"INSERT INTO device_owners (token, tenant, created_at) VALUES (?, ?, ?)",
+		token, tenant, time.Now().UTC().Format(time.RFC3339),
+	)
+	if err != nil {
+		return false, err
+	}
+	s.owners[token] = tenant
+	return true, nil
+}
+
+// SetOwner assigns the device to tenant, replacing any owner, or releases it
+// if tenant is empty.
+func (s *Store) SetOwner(token, tenant string) error {
+	if tenant == "" {
+		return s.Release(token)
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	_, err := s.db.Exec(`
+		INSERT INTO device_owners (token, tenant, created_at) VALUES (?, ?, ?)
+		ON CONFLICT(token) DO UPDATE SET tenant = excluded.tenant
+	`, token, tenant, time.Now().UTC().Format(time.RFC3339))
+	if err != nil {
+		return err
+	}
+	s.owners[token] = tenant
+	return nil
+}
+
+// Release removes the device's owner, if any.
+func (s *Store) Release(token string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := s.db.Exec("DELETE FROM device_owners WHERE token = ?", token); err != nil {
+		return err
+	}
+	delete(s.owners, token)
+	return nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+type scanner interface {
+	Scan(dest ...any) error
+}
+
+func scanKey(row scanner) (*Key, error) {
+	var k Key
+	var scopes, tokens string
+	if err := row.Scan(&k.ID, &k.Name, &k.Prefix, &k.Tenant, &scopes, &tokens, &k.CreatedAt); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(scopes), &k.Scopes); err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal([]byte(tokens), &k.Tokens); err != nil {
+		return nil, err
+	}
+	return &k, nil
+}
+
+// hash returns the hex SHA-256 of a secret. Secrets are random, so a fast
+// unsalted hash is enough to make a leaked database useless.
+func hash(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
@@ -0,0 +1,139 @@
+package apikey
+
+import (
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStore_CreateAuthenticateDelete(t *testing.T) {
+	s, err := NewStore(filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	k := &Key{Name: "partner", Scopes: []string{ScopeMessagesSend}, Tokens: []string{"60123456789"}}
+	secret, err := s.Create(k)
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if !strings.HasPrefix(secret, k.Prefix) || k.ID == "" {
+		t.Fatalf("unexpected key: secret=%q %+v", secret, k)
+	}
+
+	got, err := s.Authenticate(secret)
+	if err != nil {
+		t.Fatalf("authenticate failed: %v", err)
+	}
+	if got.Name != "partner" || !got.HasScope(ScopeMessagesSend) || got.HasScope(ScopeAdmin) {
+		t.Fatalf("unexpected key: %+v", got)
+	}
+	if !got.AllowsToken("60123456789") || got.AllowsToken("60198765432") {
+		t.Fatalf("unexpected token restriction: %+v", got.Tokens)
+	}
+	if _, err := s.Authenticate(secret + "x"); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound for wrong secret, got %v", err)
+	}
+
+	list, err := s.List()
+	if err != nil || len(list) != 1 || list[0].ID != k.ID {
+		t.Fatalf("unexpected list: %+v err=%v", list, err)
+	}
+
+	if err := s.Delete(k.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if _, err := s.Authenticate(secret); err != ErrNotFound {
+		t.Fatalf("expected revoked key to fail, got %v", err)
+	}
+	if err := s.Delete(k.ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestKey_Validate(t *testing.T) {
+	tests := []struct {
+		name string
+		key  Key
+		ok   bool
+	}{
+		{"valid", Key{Name: "ci", Scopes: []string{ScopeDevicesRead}}, true},
+		{"missing name", Key{Scopes: []string{ScopeAdmin}}, false},
+		{"no scopes", Key{Name: "ci"}, false},
+		{"unknown scope", Key{Name: "ci", Scopes: []string{"devices:delete"}}, false},
+		{"invalid token", Key{Name: "ci", Scopes: []string{ScopeAdmin}, Tokens: []string{"abc"}}, false},
+		{"tenant", Key{Name: "ci", Tenant: "team-a", Scopes: []string{ScopeDevicesWrite}}, true},
+		{"invalid tenant", Key{Name: "ci", Tenant: "Team A", Scopes: []string{ScopeDevicesWrite}}, false},
+		{"tenant admin", Key{Name: "ci", Tenant: "team-a", Scopes: []string{ScopeAdmin}}, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.key.Validate(); (err == nil) != tt.ok {
+				t.Errorf("Validate() = %v, want ok=%v", err, tt.ok)
+			}
+		})
+	}
+}
+
+func TestKey_AdminHasEveryScope(t *testing.T) {
+	for _, s := range Scopes {
+		if !Master.HasScope(s) {
+			t.Errorf("expected master key to have %s", s)
+		}
+	}
+	if !Master.AllowsToken("60123456789") {
+		t.Error("expected master key to allow every device")
+	}
+}
+
+func TestStore_TenantOwnership(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "keys.db")
+	s, err := NewStore(path)
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+
+	secret, err := s.Create(&Key{Name: "team-a", Tenant: "team-a", Scopes: []string{ScopeMessagesSend}})
+	if err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	p, err := s.Authenticate(secret)
+	if err != nil {
+		t.Fatalf("authenticate failed: %v", err)
+	}
+	if p.Tenant != "team-a" || p.CanUse("60123456789") {
+		t.Fatalf("expected tenant key without devices, got %+v", p.Key)
+	}
+
+	if claimed, err := s.Claim("60123456789", "team-a"); err != nil || !claimed {
+		t.Fatalf("expected claim to succeed, got claimed=%v err=%v", claimed, err)
+	}
+	if claimed, err := s.Claim("60123456789", "team-a"); err != nil || claimed {
+		t.Fatalf("expected repeat claim to be a no-op, got claimed=%v err=%v", claimed, err)
+	}
+	if _, err := s.Claim("60123456789", "team-b"); err != ErrOwned {
+		t.Fatalf("expected ErrOwned, got %v", err)
+	}
+	if !p.CanUse("60123456789") {
+		t.Fatal("expected tenant key to use its device")
+	}
+
+	// Ownership survives a restart
+	_ = s.Close()
+	s, err = NewStore(path)
+	if err != nil {
+		t.Fatalf("failed to reopen store: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+	if owner := s.Owner("60123456789"); owner != "team-a" {
+		t.Fatalf("expected owner team-a after reopen, got %q", owner)
+	}
+
+	if err := s.SetOwner("60123456789", "team-b"); err != nil || s.Owner("60123456789") != "team-b" {
+		t.Fatalf("expected reassignment to team-b, got %q err=%v", s.Owner("60123456789"), err)
+	}
+	if err := s.Release("60123456789"); err != nil || s.Owner("60123456789") != "" {
+		t.Fatalf("expected device to be released, got %q err=%v", s.Owner("60123456789"), err)
+	}
+}
@@ -0,0 +1,51 @@
+package audit
+
+import "encoding/json"
+
+// deviceEvents maps the webhook events for actions WhatsApp takes on a
+// device, rather than an API client, to audit actions.
+var deviceEvents = map[string]string{
+	"device.logged_out": "device.logged_out",
+}
+
+// Sink records WhatsApp-initiated device actions, such as a logout from the
+// phone, by receiving the webhook dispatcher's events.
+type Sink struct {
+	store *Store
+}
+
+func NewSink(store *Store) *Sink {
+	return &Sink{store: store}
+}
+
+func (s *Sink) Name() string {
+	return "audit"
+}
+
+func (s *Sink) Publish(event, token, id string, payload []byte) error {
+	action, ok := deviceEvents[event]
+	if !ok {
+		return nil
+	}
+
+	var body struct {
+		Data struct {
+			Reason string `json:"reason"`
+		} `json:"data"`
+	}
+	_ = json.Unmarshal(payload, &body)
+
+	return s.store.Record(&Entry{
+		Action:  action,
+		Actor:   "whatsapp",
+		Source:  SourceWhatsApp,
+		Target:  token,
+		Success: true,
+		Detail:  body.Data.Reason,
+	})
+}
+
+// Close is a no-op; the store is closed by its owner.
+func (s *Sink) Close() error {
+	return nil
+}
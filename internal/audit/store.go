@@ -0,0 +1,203 @@
+package audit
+
+import (
+	"database/sql"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+// Sources of audited actions.
+const (
+	SourceAPI       = "api"
+	SourceWebSocket = "websocket"
+	SourceWhatsApp  = "whatsapp"
+)
+
+// Entry is one audited action. Actor is the API key name ("master" for
+// API_KEY, the subject for JWTs, "whatsapp" for actions taken by WhatsApp);
+// Target is the device token or resource the action applied to.
+type Entry struct {
+	ID        int64  `json:"id"`
+	Action    string `json:"action"`
+	Actor     string `json:"actor"`
+	KeyID     string `json:"keyId,omitempty"`
+	IP        string `json:"ip,omitempty"`
+	Source    string `json:"source"`
+	Target    string `json:"target,omitempty"`
+	Success   bool   `json:"success"`
+	Status    int    `json:"status,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// Filter narrows the audit log. Action accepts "*" wildcards like webhook
+// event patterns; Since and Until are RFC 3339 times. A zero Limit returns
+// every match.
+type Filter struct {
+	Action string
+	Actor  string
+	Target string
+	Source string
+	Status string
+	Since  string
+	Until  string
+	Limit  int
+	Offset int
+}
+
+// Store is an append-only audit log. Triggers reject updates and deletes, so
+// entries can only be removed by deleting the database file.
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(dbPath string) (*Store, error) {
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS audit_log (
+			id         INTEGER PRIMARY KEY AUTOINCREMENT,
+			action     TEXT NOT NULL,
+			actor      TEXT NOT NULL,
+			key_id     TEXT NOT NULL DEFAULT '',
+			ip         TEXT NOT NULL DEFAULT '',
+			source     TEXT NOT NULL,
+			target     TEXT NOT NULL DEFAULT '',
+			success    INTEGER NOT NULL,
+			status     INTEGER NOT NULL DEFAULT 0,
+			detail     TEXT NOT NULL DEFAULT '',
+			created_at TEXT NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_audit_log_created_at ON audit_log(created_at);
+		CREATE TRIGGER IF NOT EXISTS audit_log_no_update BEFORE UPDATE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'audit log is append-only');
+		END;
+		CREATE TRIGGER IF NOT EXISTS audit_log_no_delete BEFORE DELETE ON audit_log
+		BEGIN
+			SELECT RAISE(ABORT, 'audit log is append-only');
+		END;
+	`)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Record appends an entry, setting its ID and CreatedAt.
+func (s *Store) Record(e *Entry) error {
+	e.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	res, err := s.db.Exec(`
+		INSERT INTO audit_log (action, actor, key_id, ip, source, target, success, status, detail, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, e.Action, e.Actor, e.KeyID, e.IP, e.Source, e.Target, e.Success, e.Status, e.Detail, e.CreatedAt)
+	if err != nil {
+		return err
+	}
+	e.ID, err = res.LastInsertId()
+	return err
+}
+
+// List returns matching entries, newest first, and the total number of
+// matches.
+func (s *Store) List(f Filter) ([]Entry, int, error) {
+	where, args := f.where()
+
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM audit_log"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	entries := []Entry{}
+	err := s.Each(f, func(e Entry) error {
+		entries = append(entries, e)
+		return nil
+	})
+	if err != nil {
+		return nil, 0, err
+	}
+	return entries, total, nil
+}
+
+// Each calls fn for every matching entry, newest first, without loading the
+// whole log into memory. It stops at the first error fn returns.
+func (s *Store) Each(f Filter, fn func(Entry) error) error {
+	where, args := f.where()
+	limit := f.Limit
+	if limit <= 0 {
+		limit = -1 // no limit
+	}
+
+	rows, err := s.db.Query(`
+		SELECT id, action, actor, key_id, ip, source, target, success, status, detail, created_at
+		FROM audit_log`+where+` ORDER BY id DESC LIMIT ? OFFSET ?
+	`, append(args, limit, f.Offset)...)
+	if err != nil {
+		return err
+	}
+	defer func() { _ = rows.Close() }()
+
+	for rows.Next() {
+		var e Entry
+		if err := rows.Scan(&e.ID, &e.Action, &e.Actor, &e.KeyID, &e.IP, &e.Source, &e.Target, &e.Success, &e.Status, &e.Detail, &e.CreatedAt); err != nil {
+			return err
+		}
+		if err := fn(e); err != nil {
+			return err
+		}
+	}
+	return rows.Err()
+}
+
+func (f Filter) where() (string, []interface{}) {
+	var conds []string
+	var args []interface{}
+	if f.Action != "" {
+		// GLOB treats "*" like webhook event patterns; actions never contain "?" or "["
+		conds = append(conds, "action GLOB ?")
+		args = append(args, f.Action)
+	}
+	if f.Actor != "" {
+		conds = append(conds, "actor = ?")
+		args = append(args, f.Actor)
+	}
+	if f.Target != "" {
+		conds = append(conds, "target = ?")
+		args = append(args, f.Target)
+	}
+	if f.Source != "" {
+		conds = append(conds, "source = ?")
+		args = append(args, f.Source)
+	}
+	switch f.Status {
+	case "success":
+		conds = append(conds, "success = 1")
+	case "failed":
+		conds = append(conds, "success = 0")
+	}
+	// Timestamps are stored as UTC RFC 3339, so they compare as strings
+	if f.Since != "" {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, f.Since)
+	}
+	if f.Until != "" {
+		conds = append(conds, "created_at < ?")
+		args = append(args, f.Until)
+	}
+
+	if len(conds) == 0 {
+		return "", args
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,107 @@
+package audit
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := NewStore(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestStore_RecordAndFilter(t *testing.T) {
+	s := newTestStore(t)
+
+	log := []Entry{
+		{Action: "device.connect", Actor: "master", Source: SourceAPI, Target: "60123456789", Success: true, Status: 200},
+		{Action: "message.send", Actor: "partner", Source: SourceWebSocket, Target: "60123456789", Success: false},
+		{Action: "device.disconnect", Actor: "partner", Source: SourceAPI, Target: "60198765432", Success: false, Status: 403},
+	}
+	for i := range log {
+		if err := s.Record(&log[i]); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+		if log[i].ID == 0 || log[i].CreatedAt == "" {
+			t.Fatalf("expected ID and CreatedAt to be set: %+v", log[i])
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter Filter
+		want   int
+	}{
+		{"all", Filter{}, 3},
+		{"action pattern", Filter{Action: "device.*"}, 2},
+		{"actor", Filter{Actor: "partner"}, 2},
+		{"target", Filter{Target: "60198765432"}, 1},
+		{"source", Filter{Source: SourceWebSocket}, 1},
+		{"failed", Filter{Status: "failed"}, 2},
+		{"since future", Filter{Since: time.Now().Add(time.Hour).UTC().Format(time.RFC3339)}, 0},
+		{"until future", Filter{Until: time.Now().Add(time.Hour).UTC().Format(time.RFC3339)}, 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			entries, total, err := s.List(tt.filter)
+			if err != nil {
+				t.Fatalf("list failed: %v", err)
+			}
+			if total != tt.want || len(entries) != tt.want {
+				t.Errorf("expected %d entries, got %d (total %d)", tt.want, len(entries), total)
+			}
+		})
+	}
+
+	entries, total, err := s.List(Filter{Limit: 1})
+	if err != nil || total != 3 || len(entries) != 1 || entries[0].Action != "device.disconnect" {
+		t.Fatalf("expected newest entry first with total 3, got %+v total=%d err=%v", entries, total, err)
+	}
+}
+
+func TestStore_AppendOnly(t *testing.T) {
+	s := newTestStore(t)
+
+	e := &Entry{Action: "cache.clear", Actor: "master", Source: SourceAPI, Success: true}
+	if err := s.Record(e); err != nil {
+		t.Fatalf("record failed: %v", err)
+	}
+
+	if _, err := s.db.Exec("UPDATE audit_log SET actor = 'someone' WHERE id = ?", e.ID); err == nil {
+		t.Error("expected update to be rejected")
+	}
+	if _, err := s.db.Exec("DELETE FROM audit_log WHERE id = ?", e.ID); err == nil {
+		t.Error("expected delete to be rejected")
+	}
+	if _, total, _ := s.List(Filter{}); total != 1 {
+		t.Errorf("expected entry to remain, got %d entries", total)
+	}
+}
+
+func TestSink_RecordsLogouts(t *testing.T) {
+	s := newTestStore(t)
+	sink := NewSink(s)
+
+	if err := sink.Publish("message.received", "60123456789", "id-1", []byte(`{"event":"message.received"}`)); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+	payload := []byte(`{"event":"device.logged_out","token":"60123456789","data":{"reason":"logged out"}}`)
+	if err := sink.Publish("device.logged_out", "60123456789", "id-2", payload); err != nil {
+		t.Fatalf("publish failed: %v", err)
+	}
+
+	entries, total, err := s.List(Filter{})
+	if err != nil || total != 1 {
+		t.Fatalf("expected one entry, got %d err=%v", total, err)
+	}
+	e := entries[0]
+	if e.Action != "device.logged_out" || e.Actor != "whatsapp" || e.Source != SourceWhatsApp || e.Target != "60123456789" || e.Detail != "logged out" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+}
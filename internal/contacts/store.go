@@ -2,6 +2,7 @@ package contacts
 
 import (
 	"database/sql"
+	"errors"
 	"time"
 
 	_ "modernc.org/sqlite"
@@ -32,6 +33,12 @@ func NewStore(dbPath string) (*Store, error) {
 			source     TEXT DEFAULT 'message',
 			first_seen DATETIME DEFAULT CURRENT_TIMESTAMP,
 			last_seen  DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+
+		-- Kept apart from contacts so databases created before it still open
+		CREATE TABLE IF NOT EXISTS away_messages (
+			phone        TEXT PRIMARY KEY,
+			away_sent_at DATETIME NOT NULL
 		)
 	`)
 	if err != nil {
@@ -54,6 +61,44 @@ func (s *Store) Upsert(phone, name, source string) error {
 	return err
 }
 
+// Get returns the contact for phone, or nil if it has never been seen.
+func (s *Store) Get(phone string) (*Contact, error) {
+	var c Contact
+	err := s.db.QueryRow(
+		"SELECT phone, name, source, first_seen, last_seen FROM contacts WHERE phone = ?", phone,
+	).Scan(&c.Phone, &c.Name, &c.Source, &c.FirstSeen, &c.LastSeen)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &c, nil
+}
+
+// AwaySentAt returns when the contact was last sent the away message, or the
+// zero time if it never was.
+func (s *Store) AwaySentAt(phone string) (time.Time, error) {
+	var sentAt string
+	err := s.db.QueryRow("SELECT away_sent_at FROM away_messages WHERE phone = ?", phone).Scan(&sentAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return time.Time{}, nil
+	}
+	if err != nil {
+		return time.Time{}, err
+	}
+	return time.Parse(time.RFC3339, sentAt)
+}
+
+// MarkAwaySent records that the contact was sent the away message at t.
+func (s *Store) MarkAwaySent(phone string, t time.Time) error {
+	_, err := s.db.Exec(`
+		INSERT INTO away_messages (phone, away_sent_at) VALUES (?, ?)
+		ON CONFLICT(phone) DO UPDATE SET away_sent_at = excluded.away_sent_at
+	`, phone, t.UTC().Format(time.RFC3339))
+	return err
+}
+
 func (s *Store) GetAll(limit, offset int) ([]Contact, int, error) {
 	var total int
 	err := s.db.QueryRow("SELECT COUNT(*) FROM contacts").Scan(&total)
@@ -0,0 +1,108 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"strconv"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/audit"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
+)
+
+// Audit serves the audit log.
+type Audit struct {
+	store  *audit.Store
+	logger zerolog.Logger
+}
+
+func NewAudit(store *audit.Store, logger zerolog.Logger) *Audit {
+	return &Audit{store: store, logger: logger}
+}
+
+// List returns audit entries, newest first. With format=jsonl, every
+// matching entry is streamed as JSON Lines instead, for export.
+func (h *Audit) List(c *fiber.Ctx) error {
+	format := c.Query("format", "json")
+	if format != "json" && format != "jsonl" {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_FORMAT", "Format must be 'json' or 'jsonl'")
+	}
+
+	f := audit.Filter{
+		Action: c.Query("action"),
+		Actor:  c.Query("actor"),
+		Target: c.Query("target"),
+		Source: c.Query("source"),
+		Status: c.Query("status"),
+	}
+	if f.Status != "" && f.Status != "success" && f.Status != "failed" {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "status must be 'success' or 'failed'")
+	}
+	for _, q := range []struct {
+		name string
+		dst  *string
+	}{{"since", &f.Since}, {"until", &f.Until}} {
+		v := c.Query(q.name)
+		if v == "" {
+			continue
+		}
+		t, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", q.name+" must be an RFC 3339 time")
+		}
+		*q.dst = t.UTC().Format(time.RFC3339)
+	}
+
+	f.Offset, _ = strconv.Atoi(c.Query("offset", "0"))
+	if f.Offset < 0 {
+		f.Offset = 0
+	}
+
+	if format == "jsonl" {
+		// Exports are unlimited unless a limit is given
+		f.Limit, _ = strconv.Atoi(c.Query("limit", "0"))
+		return h.export(c, f)
+	}
+
+	f.Limit, _ = strconv.Atoi(c.Query("limit", "100"))
+	if f.Limit < 1 {
+		f.Limit = 100
+	}
+	if f.Limit > 1000 {
+		f.Limit = 1000
+	}
+
+	entries, total, err := h.store.List(f)
+	if err != nil {
+		h.logger.Error().Err(err).Msg("failed to list audit log")
+		return response.Error(c, fiber.StatusInternalServerError, "FETCH_FAILED", "Failed to retrieve audit log")
+	}
+
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"entries": entries,
+		"total":   total,
+		"limit":   f.Limit,
+		"offset":  f.Offset,
+	}, "Audit log retrieved")
+}
+
+// export streams entries as JSON Lines. The status is already sent when the
+// stream starts, so a read error truncates the export and is only logged.
+func (h *Audit) export(c *fiber.Ctx, f audit.Filter) error {
+	c.Set(fiber.HeaderContentType, "application/x-ndjson")
+	c.Set(fiber.HeaderContentDisposition, `attachment; filename="audit.jsonl"`)
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		enc := json.NewEncoder(w)
+		err := h.store.Each(f, func(e audit.Entry) error {
+			return enc.Encode(e)
+		})
+		if err != nil {
+			h.logger.Error().Err(err).Msg("audit log export failed")
+		}
+		_ = w.Flush()
+	})
+	return nil
+}
@@ -0,0 +1,112 @@
+package handler
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/audit"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/middleware"
+)
+
+func TestAudit_RecordsAndExports(t *testing.T) {
+	logger := zerolog.New(io.Discard)
+	keys, err := apikey.NewStore(filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatalf("failed to create key store: %v", err)
+	}
+	defer func() { _ = keys.Close() }()
+	auditLog, err := audit.NewStore(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("failed to create audit log: %v", err)
+	}
+	defer func() { _ = auditLog.Close() }()
+
+	secret, err := keys.Create(&apikey.Key{Name: "partner", Scopes: []string{apikey.ScopeMessagesSend}})
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+
+	auth := middleware.NewAuth("master-key", keys, nil)
+	audited := middleware.NewAudit(auditLog, logger).Record
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	api := app.Group("", auth.Require())
+	api.Post("/messages", audited("message.send"), middleware.Scope(apikey.ScopeMessagesSend), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	api.Delete("/cache", audited("cache.clear"), middleware.Scope(apikey.ScopeAdmin), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+	api.Get("/audit", middleware.Scope(apikey.ScopeAdmin), NewAudit(auditLog, logger).List)
+
+	do := func(method, path, key, body string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	do(http.MethodPost, "/messages", secret, `{"token":"60123456789","to":"60198765432","text":"hi"}`)
+	if resp := do(http.MethodDelete, "/cache", secret, ""); resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected partner to be denied, got %d", resp.StatusCode)
+	}
+	do(http.MethodDelete, "/cache", "master-key", "")
+
+	resp := do(http.MethodGet, "/audit?action=cache.*&status=failed", "master-key", "")
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	var listed struct {
+		Data struct {
+			Entries []audit.Entry `json:"entries"`
+			Total   int           `json:"total"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&listed); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	if listed.Data.Total != 1 {
+		t.Fatalf("expected 1 denied cache clear, got %+v", listed.Data)
+	}
+	if e := listed.Data.Entries[0]; e.Actor != "partner" || e.Status != http.StatusForbidden || e.Source != audit.SourceAPI || e.IP == "" {
+		t.Errorf("unexpected entry: %+v", e)
+	}
+
+	resp = do(http.MethodGet, "/audit?format=jsonl", "master-key", "")
+	if ct := resp.Header.Get("Content-Type"); ct != "application/x-ndjson" {
+		t.Fatalf("expected JSON Lines, got %q", ct)
+	}
+	var lines []audit.Entry
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		var e audit.Entry
+		if err := json.Unmarshal(scanner.Bytes(), &e); err != nil {
+			t.Fatalf("invalid line %q: %v", scanner.Text(), err)
+		}
+		lines = append(lines, e)
+	}
+	if len(lines) != 3 {
+		t.Fatalf("expected 3 exported entries, got %d", len(lines))
+	}
+	if send := lines[2]; send.Action != "message.send" || send.Target != "60123456789" || !send.Success {
+		t.Errorf("unexpected send entry: %+v", send)
+	}
+
+	if resp := do(http.MethodGet, "/audit?since=yesterday", "master-key", ""); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("expected 400 for invalid since, got %d", resp.StatusCode)
+	}
+}
@@ -0,0 +1,308 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"net"
+
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/audit"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/ws"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
+)
+
+// Commands executes WebSocket commands against device sessions, mirroring
+// the validation of the equivalent REST endpoints.
+type Commands struct {
+	manager   *whatsapp.DeviceManager
+	validator *validator.Validator
+	audit     *audit.Store
+	logger    zerolog.Logger
+}
+
+// NewCommands creates the command handler. Commands are recorded in auditLog
+// unless it is nil.
+func NewCommands(manager *whatsapp.DeviceManager, v *validator.Validator, auditLog *audit.Store, logger zerolog.Logger) *Commands {
+	return &Commands{manager: manager, validator: v, audit: auditLog, logger: logger}
+}
+
+type sendCommand struct {
+	Token  string           `json:"token"`
+	To     string           `json:"to"`
+	Text   string           `json:"text"`
+	Typing *whatsapp.Typing `json:"typing"`
+}
+
+type markReadCommand struct {
+	Token      string   `json:"token"`
+	Chat       string   `json:"chat"`
+	MessageIDs []string `json:"messageIds"`
+}
+
+type presenceCommand struct {
+	Token string `json:"token"`
+	To    string `json:"to"`
+	State string `json:"state"`
+}
+
+type qrCommand struct {
+	Token string `json:"token"`
+}
+
+// commandScopes is the API key scope each command requires, matching the
+// equivalent REST endpoint.
+var commandScopes = map[string]string{
+	"send":     apikey.ScopeMessagesSend,
+	"markRead": apikey.ScopeMessagesSend,
+	"presence": apikey.ScopeMessagesSend,
+	"qr":       apikey.ScopeDevicesWrite,
+}
+
+// commandActions is the audit action recorded for each command, matching
+// the equivalent REST endpoint.
+var commandActions = map[string]string{
+	"send":     "message.send",
+	"markRead": "message.read",
+	"presence": "presence.set",
+	"qr":       "device.qr",
+}
+
+// commandAction returns the audit action for a command. presence with "to"
+// sends a chat state, like POST /presence/chat.
+func commandAction(command string, params json.RawMessage) string {
+	if command == "presence" {
+		var p struct {
+			To string `json:"to"`
+		}
+		if json.Unmarshal(params, &p) == nil && p.To != "" {
+			return "presence.chat"
+		}
+	}
+	return commandActions[command]
+}
+
+func commandError(code, message string) error {
+	return &ws.CommandError{Code: code, Message: message}
+}
+
+func (h *Commands) HandleCommand(ctx context.Context, command string, params json.RawMessage) (data interface{}, err error) {
+	scope, ok := commandScopes[command]
+	if !ok {
+		return nil, commandError("UNKNOWN_COMMAND", "Unknown command: "+command)
+	}
+	if action := commandAction(command, params); action != "" {
+		defer func() { h.record(ctx, action, command, params, err) }()
+	}
+	if p, ok := ws.PrincipalFrom(ctx).(interface{ HasScope(string) bool }); ok && !p.HasScope(scope) {
+		return nil, commandError("INSUFFICIENT_SCOPE", "API key lacks the "+scope+" scope")
+	}
+
+	switch command {
+	case "send":
+		var p sendCommand
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return h.send(ctx, p)
+	case "markRead":
+		var p markReadCommand
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return h.markRead(ctx, p)
+	case "presence":
+		var p presenceCommand
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return h.presence(ctx, p)
+	case "qr":
+		var p qrCommand
+		if err := decodeParams(params, &p); err != nil {
+			return nil, err
+		}
+		return h.qr(ctx, p)
+	default:
+		return nil, commandError("UNKNOWN_COMMAND", "Unknown command: "+command)
+	}
+}
+
+// record adds a command to the audit log, including rejected attempts.
+func (h *Commands) record(ctx context.Context, action, command string, params json.RawMessage, err error) {
+	if h.audit == nil {
+		return
+	}
+
+	entry := &audit.Entry{
+		Action:  action,
+		Actor:   apikey.Master.Name,
+		IP:      ws.RemoteAddr(ctx),
+		Source:  audit.SourceWebSocket,
+		Success: err == nil,
+		Detail:  command,
+	}
+	if p, ok := ws.PrincipalFrom(ctx).(*apikey.Principal); ok {
+		entry.Actor, entry.KeyID = p.Name, p.ID
+	}
+	if host, _, splitErr := net.SplitHostPort(entry.IP); splitErr == nil {
+		entry.IP = host
+	}
+	var target struct {
+		Token string `json:"token"`
+	}
+	if json.Unmarshal(params, &target) == nil {
+		entry.Target = target.Token
+	}
+	var cmdErr *ws.CommandError
+	if errors.As(err, &cmdErr) {
+		entry.Detail += ": " + cmdErr.Code
+	}
+
+	if recErr := h.audit.Record(entry); recErr != nil {
+		h.logger.Error().Err(recErr).Str("action", action).Msg("failed to record audit entry")
+	}
+}
+
+func decodeParams(params json.RawMessage, v interface{}) error {
+	if len(params) == 0 {
+		return commandError("INVALID_REQUEST", "params are required")
+	}
+	if err := json.Unmarshal(params, v); err != nil {
+		return commandError("INVALID_REQUEST", "Invalid params")
+	}
+	return nil
+}
+
+// session resolves a device token the client may use; connected requires a
+// live WhatsApp connection.
+func (h *Commands) session(ctx context.Context, token string, connected bool) (*whatsapp.DeviceSession, error) {
+	if token == "" {
+		return nil, commandError("MISSING_TOKEN", "Token is required")
+	}
+	if err := validator.ValidateToken(token); err != nil {
+		return nil, commandError("INVALID_TOKEN", "Token must be a phone number (7-15 digits)")
+	}
+	if !ws.PrincipalFrom(ctx).CanUse(token) {
+		return nil, commandError("TOKEN_NOT_ALLOWED", "API key is not allowed to use this device")
+	}
+	session, ok := h.manager.GetSession(token)
+	if !ok {
+		return nil, commandError("DEVICE_NOT_FOUND", "Device not found")
+	}
+	if connected && session.GetStatus() != whatsapp.StatusConnected {
+		return nil, commandError("DEVICE_NOT_CONNECTED", "Device is not connected")
+	}
+	return session, nil
+}
+
+func (h *Commands) send(ctx context.Context, p sendCommand) (interface{}, error) {
+	session, err := h.session(ctx, p.Token, true)
+	if err != nil {
+		return nil, err
+	}
+	if err := h.validator.ValidateMessage(p.Text); err != nil {
+		return nil, commandError("INVALID_MESSAGE", "Message text cannot be empty")
+	}
+	if p.Typing != nil {
+		if err := p.Typing.Validate(); err != nil {
+			return nil, commandError("INVALID_TYPING", "Invalid typing: "+err.Error())
+		}
+	}
+	phone, err := h.validator.ValidatePhone(p.To)
+	if err != nil {
+		return nil, commandError("INVALID_PHONE", "Invalid phone number")
+	}
+
+	result, err := session.SendText(ctx, phone, p.Text, p.Typing)
+	if errors.Is(err, whatsapp.ErrRecipientOptedOut) {
+		return nil, commandError("RECIPIENT_OPTED_OUT", "Recipient has opted out of messages from this device")
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("token", p.Token).Str("to", phone).Msg("failed to send message via command")
+		return nil, commandError("SEND_FAILED", "Failed to send message")
+	}
+
+	return map[string]interface{}{
+		"messageId": result.ID,
+		"timestamp": result.Timestamp,
+	}, nil
+}
+
+func (h *Commands) markRead(ctx context.Context, p markReadCommand) (interface{}, error) {
+	session, err := h.session(ctx, p.Token, true)
+	if err != nil {
+		return nil, err
+	}
+	if len(p.MessageIDs) == 0 {
+		return nil, commandError("INVALID_REQUEST", "messageIds is required")
+	}
+	chat, err := h.validator.ValidatePhone(p.Chat)
+	if err != nil {
+		return nil, commandError("INVALID_PHONE", "Invalid phone number")
+	}
+
+	if err := session.MarkRead(ctx, chat, p.MessageIDs); err != nil {
+		h.logger.Error().Err(err).Str("token", p.Token).Str("chat", chat).Msg("failed to mark messages read")
+		return nil, commandError("MARK_READ_FAILED", "Failed to mark messages as read")
+	}
+
+	return map[string]interface{}{
+		"chat":       chat,
+		"messageIds": p.MessageIDs,
+	}, nil
+}
+
+// presence sets global presence, or a chat state when "to" is given.
+func (h *Commands) presence(ctx context.Context, p presenceCommand) (interface{}, error) {
+	session, err := h.session(ctx, p.Token, true)
+	if err != nil {
+		return nil, err
+	}
+
+	if p.To == "" {
+		if p.State != "available" && p.State != "unavailable" {
+			return nil, commandError("INVALID_STATE", "State must be 'available' or 'unavailable'")
+		}
+		if err := session.SetPresence(ctx, p.State == "available"); err != nil {
+			h.logger.Error().Err(err).Str("token", p.Token).Msg("failed to set presence via command")
+			return nil, commandError("PRESENCE_FAILED", "Failed to set presence")
+		}
+		return map[string]interface{}{"state": p.State}, nil
+	}
+
+	switch p.State {
+	case whatsapp.ChatStateComposing, whatsapp.ChatStateRecording, whatsapp.ChatStatePaused:
+	default:
+		return nil, commandError("INVALID_STATE", "State must be 'composing', 'recording' or 'paused'")
+	}
+	phone, err := h.validator.ValidatePhone(p.To)
+	if err != nil {
+		return nil, commandError("INVALID_PHONE", "Invalid phone number")
+	}
+
+	err = session.SendChatPresence(ctx, phone, p.State)
+	if errors.Is(err, whatsapp.ErrRecipientOptedOut) {
+		return nil, commandError("RECIPIENT_OPTED_OUT", "Recipient has opted out of messages from this device")
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("token", p.Token).Str("to", phone).Msg("failed to send chat presence via command")
+		return nil, commandError("PRESENCE_FAILED", "Failed to send chat presence")
+	}
+	return map[string]interface{}{"to": phone, "state": p.State}, nil
+}
+
+func (h *Commands) qr(ctx context.Context, p qrCommand) (interface{}, error) {
+	session, err := h.session(ctx, p.Token, false)
+	if err != nil {
+		return nil, err
+	}
+	pairing := session.QR()
+	if pairing == nil {
+		return nil, commandError("QR_NOT_AVAILABLE", "No QR code available, connect the device with method 'qr' first")
+	}
+	return pairing, nil
+}
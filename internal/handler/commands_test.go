@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/config"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/audit"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/webhook"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/ws"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
+)
+
+func newTestCommands(t *testing.T, auditLog *audit.Store) *Commands {
+	t.Helper()
+	logger := zerolog.New(io.Discard)
+	cfg := &config.Config{DataDir: t.TempDir()}
+	hub := ws.NewHub(logger)
+	dispatcher, err := webhook.NewDispatcher(webhook.Config{}, logger)
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	manager := whatsapp.NewDeviceManager(cfg, hub, dispatcher, logger)
+	return NewCommands(manager, validator.New("60", 11, 12), auditLog, logger)
+}
+
+func TestCommands_Errors(t *testing.T) {
+	h := newTestCommands(t, nil)
+
+	tests := []struct {
+		command string
+		params  string
+		code    string
+	}{
+		{"reboot", `{}`, "UNKNOWN_COMMAND"},
+		{"send", ``, "INVALID_REQUEST"},
+		{"send", `[1]`, "INVALID_REQUEST"},
+		{"send", `{"to":"60198765432","text":"hi"}`, "MISSING_TOKEN"},
+		{"send", `{"token":"abc","to":"60198765432","text":"hi"}`, "INVALID_TOKEN"},
+		{"send", `{"token":"60123456789","to":"60198765432","text":"hi"}`, "DEVICE_NOT_FOUND"},
+		{"markRead", `{"token":"60123456789","chat":"60198765432","messageIds":["X"]}`, "DEVICE_NOT_FOUND"},
+		{"presence", `{"token":"60123456789","state":"available"}`, "DEVICE_NOT_FOUND"},
+		{"qr", `{"token":"60123456789"}`, "DEVICE_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		_, err := h.HandleCommand(context.Background(), tt.command, json.RawMessage(tt.params))
+		var cmdErr *ws.CommandError
+		if !errors.As(err, &cmdErr) || cmdErr.Code != tt.code {
+			t.Errorf("%s %s: expected %s, got %v", tt.command, tt.params, tt.code, err)
+		}
+	}
+}
+
+func TestCommands_Audited(t *testing.T) {
+	auditLog, err := audit.NewStore(filepath.Join(t.TempDir(), "audit.db"))
+	if err != nil {
+		t.Fatalf("failed to create audit log: %v", err)
+	}
+	defer func() { _ = auditLog.Close() }()
+	h := newTestCommands(t, auditLog)
+
+	commands := []struct{ command, params, action string }{
+		{"send", `{"token":"60123456789","to":"60198765432","text":"hi"}`, "message.send"},
+		{"markRead", `{"token":"60123456789","chat":"60198765432","messageIds":["X"]}`, "message.read"},
+		{"presence", `{"token":"60123456789","state":"available"}`, "presence.set"},
+		{"presence", `{"token":"60123456789","to":"60198765432","state":"composing"}`, "presence.chat"},
+		{"qr", `{"token":"60123456789"}`, "device.qr"},
+	}
+	for _, c := range commands {
+		_, _ = h.HandleCommand(context.Background(), c.command, json.RawMessage(c.params))
+	}
+
+	entries, total, err := auditLog.List(audit.Filter{Source: audit.SourceWebSocket})
+	if err != nil {
+		t.Fatalf("failed to list audit entries: %v", err)
+	}
+	if total != len(commands) {
+		t.Fatalf("expected %d entries, got %d", len(commands), total)
+	}
+	// Entries are listed newest first
+	for i, c := range commands {
+		e := entries[len(entries)-1-i]
+		if e.Action != c.action || e.Target != "60123456789" || e.Success {
+			t.Errorf("%s: expected failed %s entry for the device, got %+v", c.command, c.action, e)
+		}
+	}
+}
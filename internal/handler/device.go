@@ -1,26 +1,45 @@
 package handler
 
 import (
+	"errors"
+	"time"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog"
 
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/middleware"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/settings"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/qr"
 	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
 	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
 )
 
 type Device struct {
 	manager *whatsapp.DeviceManager
+	keys    *apikey.Store
 	logger  zerolog.Logger
 }
 
-func NewDevice(manager *whatsapp.DeviceManager, logger zerolog.Logger) *Device {
-	return &Device{manager: manager, logger: logger}
+// NewDevice creates the device handler. keys records which tenant owns each
+// device.
+func NewDevice(manager *whatsapp.DeviceManager, keys *apikey.Store, logger zerolog.Logger) *Device {
+	return &Device{manager: manager, keys: keys, logger: logger}
 }
 
 type connectRequest struct {
-	Token  string `json:"token"`
-	Method string `json:"method"` // "qr" or "code"
+	Token   string            `json:"token"`
+	Method  string            `json:"method"` // "qr" or "code"
+	Webhook *settings.Webhook `json:"webhook"`
+
+	// Tenant assigns the device to a tenant; tenant keys always assign
+	// their own
+	Tenant string `json:"tenant"`
+}
+
+type tenantRequest struct {
+	Tenant string `json:"tenant"`
 }
 
 func (h *Device) Connect(c *fiber.Ctx) error {
@@ -37,6 +56,23 @@ func (h *Device) Connect(c *fiber.Ctx) error {
 		return response.Error(c, fiber.StatusBadRequest, "INVALID_TOKEN", "Token must be a phone number (7-15 digits)")
 	}
 
+	p := middleware.PrincipalFrom(c)
+	if !p.AllowsToken(req.Token) {
+		return middleware.TokenNotAllowed(c)
+	}
+
+	tenant := req.Tenant
+	if p.Tenant != "" {
+		if tenant != "" && tenant != p.Tenant {
+			return response.Error(c, fiber.StatusBadRequest, "INVALID_TENANT", "Tenant keys can only connect devices for their own tenant")
+		}
+		tenant = p.Tenant
+	} else if tenant != "" {
+		if err := apikey.ValidateTenant(tenant); err != nil {
+			return response.Error(c, fiber.StatusBadRequest, "INVALID_TENANT", err.Error())
+		}
+	}
+
 	if req.Method == "" {
 		req.Method = "qr"
 	}
@@ -45,21 +81,60 @@ func (h *Device) Connect(c *fiber.Ctx) error {
 		return response.Error(c, fiber.StatusBadRequest, "INVALID_METHOD", "Method must be 'qr' or 'code'")
 	}
 
-	h.logger.Info().Str("token", req.Token).Str("method", req.Method).Msg("connecting device")
+	if req.Webhook != nil {
+		if req.Webhook.URL != "" && !canSetWebhookURL(c) {
+			return webhookURLNotAllowed(c)
+		}
+		if err := req.Webhook.Validate(); err != nil {
+			return response.Error(c, fiber.StatusBadRequest, "INVALID_WEBHOOK", err.Error())
+		}
+	}
 
-	if err := h.manager.Connect(c.Context(), req.Token, req.Method); err != nil {
+	// A tenant may take over an unowned token, but not a device that is
+	// already set up; an admin assigns those with PUT /devices/:token/tenant
+	claimed := false
+	if tenant != "" {
+		if _, exists := h.manager.GetSession(req.Token); exists && p.Tenant != "" && h.keys.Owner(req.Token) != tenant {
+			return middleware.TokenNotAllowed(c)
+		}
+		var err error
+		claimed, err = h.keys.Claim(req.Token, tenant)
+		if errors.Is(err, apikey.ErrOwned) {
+			return middleware.TokenNotAllowed(c)
+		}
+		if err != nil {
+			h.logger.Error().Err(err).Str("token", req.Token).Msg("failed to claim device")
+			return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to assign device to tenant")
+		}
+	}
+
+	h.logger.Info().Str("token", req.Token).Str("method", req.Method).Str("tenant", tenant).Msg("connecting device")
+
+	if err := h.manager.Connect(c.Context(), req.Token, req.Method, req.Webhook); err != nil {
+		if claimed {
+			_ = h.keys.Release(req.Token)
+		}
 		return response.Error(c, fiber.StatusInternalServerError, "CONNECTION_FAILED", "Failed to connect device")
 	}
 
+	data := fiber.Map{
+		"token":  req.Token,
+		"method": req.Method,
+	}
+
 	msg := "QR code sent via WebSocket"
 	if req.Method == "code" {
 		msg = "Pairing code sent via WebSocket"
+		if session, ok := h.manager.GetSession(req.Token); ok {
+			if pairing := session.PairingCode(); pairing != nil {
+				data["pairingCode"] = pairing.Code
+				data["expiresAt"] = pairing.ExpiresAt
+				msg = "Pairing code generated"
+			}
+		}
 	}
 
-	return response.Success(c, fiber.StatusOK, fiber.Map{
-		"token":  req.Token,
-		"method": req.Method,
-	}, msg)
+	return response.Success(c, fiber.StatusOK, data, msg)
 }
 
 func (h *Device) Disconnect(c *fiber.Ctx) error {
@@ -77,6 +152,125 @@ func (h *Device) Disconnect(c *fiber.Ctx) error {
 	if err := h.manager.Disconnect(c.Context(), token); err != nil {
 		return response.Error(c, fiber.StatusNotFound, "DEVICE_NOT_FOUND", "Device not found")
 	}
+	// A logged-out device can be paired again by any tenant
+	if err := h.keys.Release(token); err != nil {
+		h.logger.Error().Err(err).Str("token", token).Msg("failed to release device ownership")
+	}
 
 	return response.Success(c, fiber.StatusOK, fiber.Map{"token": token}, "Device disconnected and logged out")
 }
+
+func (h *Device) List(c *fiber.Ctx) error {
+	devices := allowedSessions(c, h.manager)
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"devices": devices,
+		"total":   len(devices),
+	}, "Devices retrieved")
+}
+
+// SetTenant assigns a device to a tenant, or releases it with an empty tenant.
+func (h *Device) SetTenant(c *fiber.Ctx) error {
+	token := c.Params("token")
+	if err := validator.ValidateToken(token); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_TOKEN", "Token must be a phone number (7-15 digits)")
+	}
+
+	var req tenantRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+	if req.Tenant != "" {
+		if err := apikey.ValidateTenant(req.Tenant); err != nil {
+			return response.Error(c, fiber.StatusBadRequest, "INVALID_TENANT", err.Error())
+		}
+	}
+
+	if err := h.keys.SetOwner(token, req.Tenant); err != nil {
+		h.logger.Error().Err(err).Str("token", token).Msg("failed to set device tenant")
+		return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to assign device to tenant")
+	}
+
+	h.logger.Info().Str("token", token).Str("tenant", req.Tenant).Msg("device tenant updated")
+	return response.Success(c, fiber.StatusOK, fiber.Map{"token": token, "tenant": req.Tenant}, "Device tenant updated")
+}
+
+func (h *Device) Get(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+	return response.Success(c, fiber.StatusOK, session.Info(), "Device retrieved")
+}
+
+// QR returns the latest QR code as a PNG or SVG image, or as JSON with format=raw.
+func (h *Device) QR(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	format := c.Query("format", "png")
+	if format != "png" && format != "svg" && format != "raw" {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_FORMAT", "Format must be 'png', 'svg' or 'raw'")
+	}
+
+	pairing := session.QR()
+	if pairing == nil {
+		return response.Error(c, fiber.StatusNotFound, "QR_NOT_AVAILABLE", "No QR code available, connect the device with method 'qr' first")
+	}
+
+	if format == "raw" {
+		return response.Success(c, fiber.StatusOK, pairing, "QR code retrieved")
+	}
+
+	size := c.QueryInt("size", 256)
+	if size < 128 || size > 1024 {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_SIZE", "Size must be between 128 and 1024")
+	}
+
+	var img []byte
+	var err error
+	if format == "svg" {
+		img, err = qr.SVG(pairing.Code, size)
+		c.Set(fiber.HeaderContentType, "image/svg+xml")
+	} else {
+		img, err = qr.PNG(pairing.Code, size)
+		c.Set(fiber.HeaderContentType, "image/png")
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("token", session.Token).Msg("failed to render QR code")
+		return response.Error(c, fiber.StatusInternalServerError, "INTERNAL_ERROR", "Failed to render QR code")
+	}
+
+	c.Set(fiber.HeaderCacheControl, "no-store")
+	c.Set("X-QR-Expires-At", pairing.ExpiresAt.Format(time.RFC3339))
+	return c.Send(img)
+}
+
+// allowedSessions lists the devices the request's API key may use.
+func allowedSessions(c *fiber.Ctx, manager *whatsapp.DeviceManager) []whatsapp.SessionInfo {
+	p := middleware.PrincipalFrom(c)
+	sessions := manager.ListSessions()
+	allowed := sessions[:0]
+	for _, s := range sessions {
+		if p.CanUse(s.Token) {
+			allowed = append(allowed, s)
+		}
+	}
+	return allowed
+}
+
+// findSession resolves the device from the :token route param. When the
+// returned session is nil, the error is the already-written error response.
+func findSession(c *fiber.Ctx, manager *whatsapp.DeviceManager) (*whatsapp.DeviceSession, error) {
+	token := c.Params("token")
+	if err := validator.ValidateToken(token); err != nil {
+		return nil, response.Error(c, fiber.StatusBadRequest, "INVALID_TOKEN", "Token must be a phone number (7-15 digits)")
+	}
+
+	session, ok := manager.GetSession(token)
+	if !ok {
+		return nil, response.Error(c, fiber.StatusNotFound, "DEVICE_NOT_FOUND", "Device not found")
+	}
+	return session, nil
+}
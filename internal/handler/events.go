@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"bufio"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/middleware"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/ws"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
+)
+
+const (
+	sseHeartbeat  = 15 * time.Second
+	sseRetryDelay = 3000 // ms, sent as the EventSource reconnect hint
+)
+
+// Events streams hub broadcasts as Server-Sent Events.
+type Events struct {
+	hub    *ws.Hub
+	logger zerolog.Logger
+}
+
+func NewEvents(hub *ws.Hub, logger zerolog.Logger) *Events {
+	return &Events{hub: hub, logger: logger}
+}
+
+func (h *Events) Stream(c *fiber.Ctx) error {
+	p := middleware.PrincipalFrom(c)
+	var tokens []string
+	for _, token := range strings.Split(c.Query("token"), ",") {
+		if token = strings.TrimSpace(token); token == "" {
+			continue
+		}
+		if err := validator.ValidateToken(token); err != nil {
+			return response.Error(c, fiber.StatusBadRequest, "INVALID_TOKEN", "Token must be a phone number (7-15 digits)")
+		}
+		if !p.CanUse(token) {
+			return middleware.TokenNotAllowed(c)
+		}
+		tokens = append(tokens, token)
+	}
+
+	// Browsers resend Last-Event-ID on reconnect; the query form allows a manual resume
+	lastID, _ := strconv.ParseUint(c.Get("Last-Event-ID", c.Query("lastEventId")), 10, 64)
+
+	listener, missed := h.hub.Listen(p, tokens, lastID)
+
+	c.Set(fiber.HeaderContentType, "text/event-stream")
+	c.Set(fiber.HeaderCacheControl, "no-cache")
+	c.Set(fiber.HeaderConnection, "keep-alive")
+	c.Set("X-Accel-Buffering", "no")
+
+	c.Context().SetBodyStreamWriter(func(w *bufio.Writer) {
+		defer h.hub.Unlisten(listener)
+
+		ticker := time.NewTicker(sseHeartbeat)
+		defer ticker.Stop()
+
+		fmt.Fprintf(w, "retry: %d\n\n", sseRetryDelay)
+		for _, evt := range missed {
+			writeSSE(w, evt)
+		}
+		if err := w.Flush(); err != nil {
+			return
+		}
+
+		for {
+			select {
+			case evt, ok := <-listener.C:
+				if !ok {
+					return
+				}
+				writeSSE(w, evt)
+			case <-ticker.C:
+				fmt.Fprint(w, ": heartbeat\n\n")
+			}
+			// A failed flush means the client went away
+			if err := w.Flush(); err != nil {
+				return
+			}
+		}
+	})
+
+	return nil
+}
+
+func writeSSE(w *bufio.Writer, evt ws.Event) {
+	fmt.Fprintf(w, "id: %d\nevent: %s\ndata: %s\n\n", evt.Message.Seq, evt.Message.Event, evt.Data)
+}
@@ -0,0 +1,66 @@
+package handler
+
+import (
+	"bufio"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestIntegration_SSEReplayAndLive(t *testing.T) {
+	wsURL, hub := startTestApp(t, "test-api-key", 5*time.Second, "*")
+	url := strings.Replace(strings.TrimSuffix(wsURL, "/ws"), "ws://", "http://", 1) + "/events?token=60123456789"
+
+	hub.Broadcast("60123456789", "qrcode", "first")
+	hub.Broadcast("60198765432", "qrcode", "other device")
+	hub.Broadcast("60123456789", "qrcode", "second")
+
+	req, _ := http.NewRequest(http.MethodGet, url, nil)
+	req.Header.Set("Last-Event-ID", "1")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("request failed: %v", err)
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if ct := resp.Header.Get("Content-Type"); ct != "text/event-stream" {
+		t.Fatalf("expected text/event-stream, got %q", ct)
+	}
+
+	lines := make(chan string, 32)
+	go func() {
+		scanner := bufio.NewScanner(resp.Body)
+		for scanner.Scan() {
+			lines <- scanner.Text()
+		}
+		close(lines)
+	}()
+
+	expectData := func(want string) {
+		t.Helper()
+		timeout := time.After(2 * time.Second)
+		for {
+			select {
+			case line, ok := <-lines:
+				if !ok {
+					t.Fatalf("stream closed before %q", want)
+				}
+				if strings.HasPrefix(line, "data: ") {
+					if !strings.Contains(line, want) {
+						t.Fatalf("expected data containing %q, got %q", want, line)
+					}
+					return
+				}
+			case <-timeout:
+				t.Fatalf("timed out waiting for %q", want)
+			}
+		}
+	}
+
+	// Replay skips event 1 and the other device's event
+	expectData(`"data":"second"`)
+
+	hub.Broadcast("60123456789", "connection-success", nil)
+	expectData(`"event":"connection-success"`)
+}
@@ -30,7 +30,7 @@ func (h *Health) Detailed(c *fiber.Ctx) error {
 	var mem runtime.MemStats
 	runtime.ReadMemStats(&mem)
 
-	sessions := h.manager.ListSessions()
+	sessions := allowedSessions(c, h.manager)
 
 	data := fiber.Map{
 		"uptime":      time.Since(startTime).String(),
@@ -0,0 +1,91 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
+)
+
+// Keys manages named API keys.
+type Keys struct {
+	store  *apikey.Store
+	logger zerolog.Logger
+}
+
+func NewKeys(store *apikey.Store, logger zerolog.Logger) *Keys {
+	return &Keys{store: store, logger: logger}
+}
+
+type keyRequest struct {
+	Name   string   `json:"name"`
+	Tenant string   `json:"tenant"`
+	Scopes []string `json:"scopes"`
+	Tokens []string `json:"tokens"`
+}
+
+func (h *Keys) List(c *fiber.Ctx) error {
+	keys, err := h.store.List()
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "FETCH_FAILED", "Failed to retrieve API keys")
+	}
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"keys":  keys,
+		"total": len(keys),
+	}, "API keys retrieved")
+}
+
+func (h *Keys) Get(c *fiber.Ctx) error {
+	key, err := h.store.Get(c.Params("id"))
+	if err != nil {
+		return h.keyError(c, err, "FETCH_FAILED", "Failed to retrieve API key")
+	}
+	return response.Success(c, fiber.StatusOK, key, "API key retrieved")
+}
+
+// Create issues a key. The secret is only returned in this response.
+func (h *Keys) Create(c *fiber.Ctx) error {
+	var req keyRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+
+	key := apikey.Key{Name: req.Name, Tenant: req.Tenant, Scopes: req.Scopes, Tokens: req.Tokens}
+	if err := key.Validate(); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_KEY", err.Error())
+	}
+
+	secret, err := h.store.Create(&key)
+	if err != nil {
+		return h.keyError(c, err, "SAVE_FAILED", "Failed to create API key")
+	}
+
+	h.logger.Info().Str("key", key.ID).Str("name", key.Name).Str("tenant", key.Tenant).Strs("scopes", key.Scopes).Msg("API key created")
+	return response.Success(c, fiber.StatusCreated, fiber.Map{
+		"key":    key,
+		"secret": secret,
+	}, "API key created; store the secret now, it cannot be retrieved again")
+}
+
+func (h *Keys) Delete(c *fiber.Ctx) error {
+	id := c.Params("id")
+	if err := h.store.Delete(id); err != nil {
+		return h.keyError(c, err, "SAVE_FAILED", "Failed to revoke API key")
+	}
+
+	h.logger.Info().Str("key", id).Msg("API key revoked")
+	return response.Success(c, fiber.StatusOK, fiber.Map{"id": id}, "API key revoked")
+}
+
+// keyError writes the error response for a store error, using code and
+// message for unexpected failures.
+func (h *Keys) keyError(c *fiber.Ctx, err error, code, message string) error {
+	if errors.Is(err, apikey.ErrNotFound) {
+		return response.Error(c, fiber.StatusNotFound, "KEY_NOT_FOUND", "API key not found")
+	}
+	h.logger.Error().Err(err).Msg("API key operation failed")
+	return response.Error(c, fiber.StatusInternalServerError, code, message)
+}
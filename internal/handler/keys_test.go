@@ -0,0 +1,160 @@
+package handler
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/jwtauth"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/middleware"
+)
+
+func TestKeys_ScopedAccess(t *testing.T) {
+	store, err := apikey.NewStore(filepath.Join(t.TempDir(), "keys.db"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer func() { _ = store.Close() }()
+
+	auth := middleware.NewAuth("master-key", store, nil)
+	keys := NewKeys(store, zerolog.New(io.Discard))
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	api := app.Group("", auth.Require())
+	api.Post("/keys", middleware.Scope(apikey.ScopeAdmin), keys.Create)
+	api.Delete("/keys/:id", middleware.Scope(apikey.ScopeAdmin), keys.Delete)
+	api.Get("/contacts/:token", middleware.Scope(apikey.ScopeContactsRead), func(c *fiber.Ctx) error {
+		return c.SendStatus(fiber.StatusOK)
+	})
+
+	do := func(method, path, key, body string) *http.Response {
+		t.Helper()
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		req.Header.Set("X-API-Key", key)
+		resp, err := app.Test(req)
+		if err != nil {
+			t.Fatalf("request failed: %v", err)
+		}
+		return resp
+	}
+
+	resp := do(http.MethodPost, "/keys", "master-key", `{"name":"partner","scopes":["contacts:read"],"tokens":["60123456789"]}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var created struct {
+		Data struct {
+			Key    apikey.Key `json:"key"`
+			Secret string     `json:"secret"`
+		} `json:"data"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&created); err != nil {
+		t.Fatalf("invalid response: %v", err)
+	}
+	secret := created.Data.Secret
+
+	tests := []struct {
+		method, path string
+		want         int
+	}{
+		{http.MethodGet, "/contacts/60123456789", http.StatusOK},
+		{http.MethodGet, "/contacts/60198765432", http.StatusForbidden},
+		{http.MethodPost, "/keys", http.StatusForbidden},
+	}
+	for _, tt := range tests {
+		if resp := do(tt.method, tt.path, secret, `{}`); resp.StatusCode != tt.want {
+			t.Errorf("%s %s: expected %d, got %d", tt.method, tt.path, tt.want, resp.StatusCode)
+		}
+	}
+
+	if resp := do(http.MethodDelete, "/keys/"+created.Data.Key.ID, "master-key", ""); resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected revoke to succeed, got %d", resp.StatusCode)
+	}
+	if resp := do(http.MethodGet, "/contacts/60123456789", secret, ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Errorf("expected revoked key to be rejected, got %d", resp.StatusCode)
+	}
+}
+
+func TestAuth_JWTBearer(t *testing.T) {
+	signer, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	jwks, _ := json.Marshal(map[string]any{"keys": []map[string]string{{
+		"kty": "RSA", "kid": "k1",
+		"n": base64.RawURLEncoding.EncodeToString(signer.N.Bytes()),
+		"e": base64.RawURLEncoding.EncodeToString(big.NewInt(int64(signer.E)).Bytes()),
+	}}})
+	jwksFile := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(jwksFile, jwks, 0o600); err != nil {
+		t.Fatalf("write JWKS: %v", err)
+	}
+	verifier, err := jwtauth.New(jwtauth.Config{JWKSFile: jwksFile, ScopeClaim: "scope", TokensClaim: "devices", TenantClaim: "tenant"}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to create verifier: %v", err)
+	}
+
+	auth := middleware.NewAuth("master-key", nil, verifier)
+	app := fiber.New(fiber.Config{DisableStartupMessage: true})
+	api := app.Group("", auth.Require())
+	api.Get("/contacts/:token", middleware.Scope(apikey.ScopeContactsRead), func(c *fiber.Ctx) error {
+		return c.SendString(middleware.PrincipalFrom(c).Name)
+	})
+
+	bearer := func(exp time.Duration) string {
+		token := jwt.NewWithClaims(jwt.SigningMethodRS256, jwt.MapClaims{
+			"sub":     "dashboard",
+			"exp":     time.Now().Add(exp).Unix(),
+			"scope":   "openid contacts:read",
+			"devices": []string{"60123456789"},
+		})
+		token.Header["kid"] = "k1"
+		s, err := token.SignedString(signer)
+		if err != nil {
+			t.Fatalf("sign: %v", err)
+		}
+		return s
+	}
+
+	tests := []struct {
+		name, path, token string
+		want              int
+	}{
+		{"allowed device", "/contacts/60123456789", bearer(time.Hour), http.StatusOK},
+		{"other device", "/contacts/60198765432", bearer(time.Hour), http.StatusForbidden},
+		{"expired", "/contacts/60123456789", bearer(-time.Hour), http.StatusUnauthorized},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tt.path, nil)
+			req.Header.Set("Authorization", "Bearer "+tt.token)
+			resp, err := app.Test(req)
+			if err != nil {
+				t.Fatalf("request failed: %v", err)
+			}
+			if resp.StatusCode != tt.want {
+				t.Fatalf("expected %d, got %d", tt.want, resp.StatusCode)
+			}
+			if tt.want == http.StatusOK {
+				if body, _ := io.ReadAll(resp.Body); string(body) != "dashboard" {
+					t.Errorf("expected principal 'dashboard', got %q", body)
+				}
+			}
+		})
+	}
+}
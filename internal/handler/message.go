@@ -1,9 +1,12 @@
 package handler
 
 import (
+	"errors"
+
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog"
 
+	"github.com/AsyrafHussin/wa-gateway-go/internal/middleware"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
 	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
 	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
@@ -23,6 +26,9 @@ type sendRequest struct {
 	Token string `json:"token"`
 	To    string `json:"to"`
 	Text  string `json:"text"`
+
+	// Typing overrides the configured typing simulation for this message
+	Typing *whatsapp.Typing `json:"typing"`
 }
 
 func (h *Message) Send(c *fiber.Ctx) error {
@@ -39,16 +45,29 @@ func (h *Message) Send(c *fiber.Ctx) error {
 		return response.Error(c, fiber.StatusBadRequest, "INVALID_TOKEN", "Token must be a phone number (7-15 digits)")
 	}
 
+	if !middleware.PrincipalFrom(c).CanUse(req.Token) {
+		return middleware.TokenNotAllowed(c)
+	}
+
 	if err := h.validator.ValidateMessage(req.Text); err != nil {
 		return response.Error(c, fiber.StatusBadRequest, "INVALID_MESSAGE", "Message text cannot be empty")
 	}
 
+	if req.Typing != nil {
+		if err := req.Typing.Validate(); err != nil {
+			return response.Error(c, fiber.StatusBadRequest, "INVALID_TYPING", "Invalid typing: "+err.Error())
+		}
+	}
+
 	phone, err := h.validator.ValidatePhone(req.To)
 	if err != nil {
 		return response.Error(c, fiber.StatusBadRequest, "INVALID_PHONE", "Invalid phone number")
 	}
 
-	result, err := h.manager.SendText(c.Context(), req.Token, phone, req.Text)
+	result, err := h.manager.SendText(c.Context(), req.Token, phone, req.Text, req.Typing)
+	if errors.Is(err, whatsapp.ErrRecipientOptedOut) {
+		return response.Error(c, fiber.StatusForbidden, "RECIPIENT_OPTED_OUT", "Recipient has opted out of messages from this device")
+	}
 	if err != nil {
 		h.logger.Error().Err(err).Str("token", req.Token).Str("to", phone).Msg("failed to send message")
 		return response.Error(c, fiber.StatusInternalServerError, "SEND_FAILED", "Failed to send message")
@@ -0,0 +1,120 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/optout"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/webhook"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
+)
+
+type OptOut struct {
+	manager   *whatsapp.DeviceManager
+	validator *validator.Validator
+	webhook   *webhook.Dispatcher
+	logger    zerolog.Logger
+}
+
+func NewOptOut(manager *whatsapp.DeviceManager, v *validator.Validator, dispatcher *webhook.Dispatcher, logger zerolog.Logger) *OptOut {
+	return &OptOut{manager: manager, validator: v, webhook: dispatcher, logger: logger}
+}
+
+type optOutRequest struct {
+	Phone string `json:"phone"`
+}
+
+func (h *OptOut) List(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	limit, _ := strconv.Atoi(c.Query("limit", "100"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	if limit < 1 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	optouts, total, err := session.OptOuts.List(limit, offset)
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "FETCH_FAILED", "Failed to retrieve opt-outs")
+	}
+
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"optouts": optouts,
+		"total":   total,
+		"limit":   limit,
+		"offset":  offset,
+	}, "Opt-outs retrieved")
+}
+
+func (h *OptOut) Add(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	var req optOutRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+
+	phone, err := h.validator.ValidatePhone(req.Phone)
+	if err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_PHONE", "Invalid phone number")
+	}
+
+	added, err := session.OptOuts.Add(phone, "api", "")
+	if err != nil {
+		h.logger.Error().Err(err).Str("token", session.Token).Msg("failed to add opt-out")
+		return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to save opt-out")
+	}
+
+	if added {
+		h.logger.Info().Str("token", session.Token).Str("phone", phone).Msg("opt-out added")
+		h.webhook.Send("contact.opted_out", session.Token, map[string]string{
+			"phone":  phone,
+			"source": "api",
+		})
+	}
+
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"phone": phone,
+		"added": added,
+	}, "Opt-out recorded")
+}
+
+func (h *OptOut) Remove(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	phone, err := h.validator.ValidatePhone(c.Params("phone"))
+	if err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_PHONE", "Invalid phone number")
+	}
+
+	err = session.OptOuts.Remove(phone)
+	if errors.Is(err, optout.ErrNotFound) {
+		return response.Error(c, fiber.StatusNotFound, "OPT_OUT_NOT_FOUND", "Phone number has not opted out")
+	}
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to remove opt-out")
+	}
+
+	h.logger.Info().Str("token", session.Token).Str("phone", phone).Msg("opt-out removed")
+	return response.Success(c, fiber.StatusOK, fiber.Map{"phone": phone}, "Opt-out removed")
+}
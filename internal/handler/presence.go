@@ -0,0 +1,150 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/middleware"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
+)
+
+type Presence struct {
+	manager   *whatsapp.DeviceManager
+	validator *validator.Validator
+	logger    zerolog.Logger
+}
+
+func NewPresence(manager *whatsapp.DeviceManager, v *validator.Validator, logger zerolog.Logger) *Presence {
+	return &Presence{manager: manager, validator: v, logger: logger}
+}
+
+type presenceRequest struct {
+	Token string `json:"token"`
+	State string `json:"state"`
+}
+
+type subscribePresenceRequest struct {
+	Token string `json:"token"`
+	To    string `json:"to"`
+}
+
+type chatPresenceRequest struct {
+	Token string `json:"token"`
+	To    string `json:"to"`
+	State string `json:"state"`
+}
+
+// Set updates the device's global presence (available/unavailable).
+func (h *Presence) Set(c *fiber.Ctx) error {
+	var req presenceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+
+	if req.Token == "" {
+		return response.Error(c, fiber.StatusBadRequest, "MISSING_TOKEN", "Token is required")
+	}
+
+	if err := validator.ValidateToken(req.Token); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_TOKEN", "Token must be a phone number (7-15 digits)")
+	}
+
+	if !middleware.PrincipalFrom(c).CanUse(req.Token) {
+		return middleware.TokenNotAllowed(c)
+	}
+
+	if req.State != "available" && req.State != "unavailable" {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_STATE", "State must be 'available' or 'unavailable'")
+	}
+
+	if err := h.manager.SetPresence(c.Context(), req.Token, req.State == "available"); err != nil {
+		h.logger.Error().Err(err).Str("token", req.Token).Msg("failed to set presence")
+		return response.Error(c, fiber.StatusInternalServerError, "PRESENCE_FAILED", "Failed to set presence")
+	}
+
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"token": req.Token,
+		"state": req.State,
+	}, "Presence updated")
+}
+
+// Subscribe requests presence.update events for a contact.
+func (h *Presence) Subscribe(c *fiber.Ctx) error {
+	var req subscribePresenceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+
+	if req.Token == "" {
+		return response.Error(c, fiber.StatusBadRequest, "MISSING_TOKEN", "Token is required")
+	}
+
+	if err := validator.ValidateToken(req.Token); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_TOKEN", "Token must be a phone number (7-15 digits)")
+	}
+
+	if !middleware.PrincipalFrom(c).CanUse(req.Token) {
+		return middleware.TokenNotAllowed(c)
+	}
+
+	phone, err := h.validator.ValidatePhone(req.To)
+	if err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_PHONE", "Invalid phone number")
+	}
+
+	if err := h.manager.SubscribePresence(c.Context(), req.Token, phone); err != nil {
+		h.logger.Error().Err(err).Str("token", req.Token).Str("to", phone).Msg("failed to subscribe to presence")
+		return response.Error(c, fiber.StatusInternalServerError, "PRESENCE_FAILED", "Failed to subscribe to presence")
+	}
+
+	return response.Success(c, fiber.StatusOK, fiber.Map{"to": phone}, "Subscribed to presence")
+}
+
+// Chat sends a chat state (composing/recording/paused) to a single contact.
+func (h *Presence) Chat(c *fiber.Ctx) error {
+	var req chatPresenceRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+
+	if req.Token == "" {
+		return response.Error(c, fiber.StatusBadRequest, "MISSING_TOKEN", "Token is required")
+	}
+
+	if err := validator.ValidateToken(req.Token); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_TOKEN", "Token must be a phone number (7-15 digits)")
+	}
+
+	if !middleware.PrincipalFrom(c).CanUse(req.Token) {
+		return middleware.TokenNotAllowed(c)
+	}
+
+	switch req.State {
+	case whatsapp.ChatStateComposing, whatsapp.ChatStateRecording, whatsapp.ChatStatePaused:
+	default:
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_STATE", "State must be 'composing', 'recording' or 'paused'")
+	}
+
+	phone, err := h.validator.ValidatePhone(req.To)
+	if err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_PHONE", "Invalid phone number")
+	}
+
+	err = h.manager.SendChatPresence(c.Context(), req.Token, phone, req.State)
+	if errors.Is(err, whatsapp.ErrRecipientOptedOut) {
+		return response.Error(c, fiber.StatusForbidden, "RECIPIENT_OPTED_OUT", "Recipient has opted out of messages from this device")
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("token", req.Token).Str("to", phone).Msg("failed to send chat presence")
+		return response.Error(c, fiber.StatusInternalServerError, "PRESENCE_FAILED", "Failed to send chat presence")
+	}
+
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"to":    phone,
+		"state": req.State,
+	}, "Chat presence sent")
+}
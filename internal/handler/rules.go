@@ -0,0 +1,206 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/rules"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
+)
+
+type Rules struct {
+	manager *whatsapp.DeviceManager
+	logger  zerolog.Logger
+}
+
+func NewRules(manager *whatsapp.DeviceManager, logger zerolog.Logger) *Rules {
+	return &Rules{manager: manager, logger: logger}
+}
+
+func (h *Rules) List(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	list, err := session.Rules.List()
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "FETCH_FAILED", "Failed to retrieve rules")
+	}
+
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"rules": list,
+		"total": len(list),
+	}, "Rules retrieved")
+}
+
+func (h *Rules) Get(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	rule, err := session.Rules.Get(c.Params("id"))
+	if errors.Is(err, rules.ErrNotFound) {
+		return response.Error(c, fiber.StatusNotFound, "RULE_NOT_FOUND", "Rule not found")
+	}
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "FETCH_FAILED", "Failed to retrieve rule")
+	}
+
+	return response.Success(c, fiber.StatusOK, rule, "Rule retrieved")
+}
+
+func (h *Rules) Create(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	rule := rules.Rule{Enabled: true}
+	if err := c.BodyParser(&rule); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+	if ok, errResp := h.validate(c, session, &rule); !ok {
+		return errResp
+	}
+
+	if err := session.Rules.Create(&rule); err != nil {
+		h.logger.Error().Err(err).Str("token", session.Token).Msg("failed to create rule")
+		return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to save rule")
+	}
+
+	h.logger.Info().Str("token", session.Token).Str("rule", rule.ID).Msg("auto-reply rule created")
+	return response.Success(c, fiber.StatusCreated, rule, "Rule created")
+}
+
+func (h *Rules) Update(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	var rule rules.Rule
+	if err := c.BodyParser(&rule); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+	rule.ID = c.Params("id")
+	if ok, errResp := h.validate(c, session, &rule); !ok {
+		return errResp
+	}
+
+	err := session.Rules.Update(&rule)
+	if errors.Is(err, rules.ErrNotFound) {
+		return response.Error(c, fiber.StatusNotFound, "RULE_NOT_FOUND", "Rule not found")
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Str("token", session.Token).Msg("failed to update rule")
+		return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to save rule")
+	}
+
+	h.logger.Info().Str("token", session.Token).Str("rule", rule.ID).Msg("auto-reply rule updated")
+	return response.Success(c, fiber.StatusOK, rule, "Rule updated")
+}
+
+func (h *Rules) Delete(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	id := c.Params("id")
+	err := session.Rules.Delete(id)
+	if errors.Is(err, rules.ErrNotFound) {
+		return response.Error(c, fiber.StatusNotFound, "RULE_NOT_FOUND", "Rule not found")
+	}
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to delete rule")
+	}
+
+	h.logger.Info().Str("token", session.Token).Str("rule", id).Msg("auto-reply rule deleted")
+	return response.Success(c, fiber.StatusOK, fiber.Map{"id": id}, "Rule deleted")
+}
+
+func (h *Rules) ListTemplates(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	templates, err := session.Rules.ListTemplates()
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "FETCH_FAILED", "Failed to retrieve templates")
+	}
+
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"templates": templates,
+		"total":     len(templates),
+	}, "Templates retrieved")
+}
+
+type templateRequest struct {
+	Body string `json:"body"`
+}
+
+func (h *Rules) SaveTemplate(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	name := c.Params("name")
+	if !rules.ValidTemplateName(name) {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_TEMPLATE", "Template name must be 1-64 lowercase letters, digits, '-' or '_'")
+	}
+
+	var req templateRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+	if req.Body == "" {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_TEMPLATE", "Template body cannot be empty")
+	}
+
+	tmpl := rules.Template{Name: name, Body: req.Body}
+	if err := session.Rules.SaveTemplate(&tmpl); err != nil {
+		h.logger.Error().Err(err).Str("token", session.Token).Msg("failed to save template")
+		return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to save template")
+	}
+
+	return response.Success(c, fiber.StatusOK, tmpl, "Template saved")
+}
+
+func (h *Rules) DeleteTemplate(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	name := c.Params("name")
+	err := session.Rules.DeleteTemplate(name)
+	if errors.Is(err, rules.ErrNotFound) {
+		return response.Error(c, fiber.StatusNotFound, "TEMPLATE_NOT_FOUND", "Template not found")
+	}
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to delete template")
+	}
+
+	return response.Success(c, fiber.StatusOK, fiber.Map{"name": name}, "Template deleted")
+}
+
+// validate checks the rule and that any referenced template exists. When it
+// reports false, the error is the already-written error response.
+func (h *Rules) validate(c *fiber.Ctx, session *whatsapp.DeviceSession, rule *rules.Rule) (bool, error) {
+	if err := rule.Validate(); err != nil {
+		return false, response.Error(c, fiber.StatusBadRequest, "INVALID_RULE", err.Error())
+	}
+	if rule.Reply.Type == rules.ReplyTemplate {
+		if _, err := session.Rules.GetTemplate(rule.Reply.Template); err != nil {
+			return false, response.Error(c, fiber.StatusBadRequest, "TEMPLATE_NOT_FOUND", "Reply template does not exist")
+		}
+	}
+	return true, nil
+}
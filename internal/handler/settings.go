@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/middleware"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
+)
+
+type Settings struct {
+	manager *whatsapp.DeviceManager
+	logger  zerolog.Logger
+}
+
+func NewSettings(manager *whatsapp.DeviceManager, logger zerolog.Logger) *Settings {
+	return &Settings{manager: manager, logger: logger}
+}
+
+func (h *Settings) Get(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	st, err := session.Settings.Get()
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "FETCH_FAILED", "Failed to retrieve settings")
+	}
+
+	return response.Success(c, fiber.StatusOK, st.Redacted(), "Settings retrieved")
+}
+
+// Update merges the request body into the stored settings; omitted fields are left unchanged.
+func (h *Settings) Update(c *fiber.Ctx) error {
+	session, errResp := findSession(c, h.manager)
+	if session == nil {
+		return errResp
+	}
+
+	st, err := session.Settings.Get()
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "FETCH_FAILED", "Failed to retrieve settings")
+	}
+
+	webhookURL := st.Webhook.URL
+	if err := c.BodyParser(st); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+	if st.Webhook.URL != webhookURL && !canSetWebhookURL(c) {
+		return webhookURLNotAllowed(c)
+	}
+	if err := st.Validate(); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_SETTINGS", err.Error())
+	}
+
+	if err := session.SaveSettings(st); err != nil {
+		h.logger.Error().Err(err).Str("token", session.Token).Msg("failed to save settings")
+		return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to save settings")
+	}
+
+	h.logger.Info().Str("token", session.Token).Msg("device settings updated")
+	return response.Success(c, fiber.StatusOK, st.Redacted(), "Settings updated")
+}
+
+// canSetWebhookURL reports whether the request may point a device's webhook
+// at a URL. The gateway POSTs to whatever host is named, including internal
+// ones, so only admin keys may choose it.
+func canSetWebhookURL(c *fiber.Ctx) bool {
+	return middleware.PrincipalFrom(c).HasScope(apikey.ScopeAdmin)
+}
+
+func webhookURLNotAllowed(c *fiber.Ctx) error {
+	return response.Error(c, fiber.StatusForbidden, "INSUFFICIENT_SCOPE", "Setting webhook.url requires the admin scope")
+}
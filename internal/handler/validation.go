@@ -5,6 +5,7 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/AsyrafHussin/wa-gateway-go/internal/cache"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/middleware"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
 	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
 	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
@@ -40,6 +41,10 @@ func (h *Validation) ValidatePhone(c *fiber.Ctx) error {
 		return response.Error(c, fiber.StatusBadRequest, "INVALID_TOKEN", "Token must be a phone number (7-15 digits)")
 	}
 
+	if !middleware.PrincipalFrom(c).CanUse(req.Token) {
+		return middleware.TokenNotAllowed(c)
+	}
+
 	phone, err := h.validator.ValidatePhone(req.Phone)
 	if err != nil {
 		return response.Error(c, fiber.StatusBadRequest, "INVALID_PHONE", "Invalid phone number")
@@ -0,0 +1,260 @@
+package handler
+
+import (
+	"errors"
+	"strconv"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/webhook"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
+)
+
+type Webhook struct {
+	dispatcher *webhook.Dispatcher
+	logger     zerolog.Logger
+}
+
+func NewWebhook(dispatcher *webhook.Dispatcher, logger zerolog.Logger) *Webhook {
+	return &Webhook{dispatcher: dispatcher, logger: logger}
+}
+
+func (h *Webhook) DeadLetters(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "100"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	if limit < 1 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	letters, total, err := h.dispatcher.DeadLetters(limit, offset)
+	if errors.Is(err, webhook.ErrDisabled) {
+		return response.Error(c, fiber.StatusNotFound, "WEBHOOKS_DISABLED", "Webhook outbox is not configured")
+	}
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "FETCH_FAILED", "Failed to retrieve dead letters")
+	}
+
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"deadLetters": letters,
+		"total":       total,
+		"limit":       limit,
+		"offset":      offset,
+	}, "Dead letters retrieved")
+}
+
+func (h *Webhook) Replay(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id < 1 {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_ID", "Dead letter ID must be a positive integer")
+	}
+
+	err = h.dispatcher.Replay(id)
+	if errors.Is(err, webhook.ErrDisabled) {
+		return response.Error(c, fiber.StatusNotFound, "WEBHOOKS_DISABLED", "Webhook outbox is not configured")
+	}
+	if errors.Is(err, webhook.ErrNotFound) {
+		return response.Error(c, fiber.StatusNotFound, "DEAD_LETTER_NOT_FOUND", "Dead letter not found")
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to replay dead letter")
+		return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to replay dead letter")
+	}
+
+	h.logger.Info().Int64("id", id).Msg("dead letter replayed")
+	return response.Success(c, fiber.StatusOK, fiber.Map{"id": id}, "Dead letter queued for delivery")
+}
+
+// Deliveries lists logged delivery attempts, filtered by event pattern,
+// device token and outcome.
+func (h *Webhook) Deliveries(c *fiber.Ctx) error {
+	limit, _ := strconv.Atoi(c.Query("limit", "100"))
+	offset, _ := strconv.Atoi(c.Query("offset", "0"))
+	if limit < 1 {
+		limit = 100
+	}
+	if limit > 1000 {
+		limit = 1000
+	}
+	if offset < 0 {
+		offset = 0
+	}
+
+	status := c.Query("status")
+	if status != "" && status != "success" && status != "failed" {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "status must be 'success' or 'failed'")
+	}
+
+	attempts, total, err := h.dispatcher.Attempts(webhook.AttemptFilter{
+		Event:  c.Query("event"),
+		Token:  c.Query("token"),
+		Status: status,
+		Limit:  limit,
+		Offset: offset,
+	})
+	if errors.Is(err, webhook.ErrDisabled) {
+		return response.Error(c, fiber.StatusNotFound, "WEBHOOKS_DISABLED", "Webhook outbox is not configured")
+	}
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "FETCH_FAILED", "Failed to retrieve deliveries")
+	}
+
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"deliveries": attempts,
+		"total":      total,
+		"limit":      limit,
+		"offset":     offset,
+	}, "Deliveries retrieved")
+}
+
+func (h *Webhook) Redeliver(c *fiber.Ctx) error {
+	id, err := strconv.ParseInt(c.Params("id"), 10, 64)
+	if err != nil || id < 1 {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_ID", "Delivery ID must be a positive integer")
+	}
+
+	err = h.dispatcher.Redeliver(id)
+	if errors.Is(err, webhook.ErrDisabled) {
+		return response.Error(c, fiber.StatusNotFound, "WEBHOOKS_DISABLED", "Webhook outbox is not configured")
+	}
+	if errors.Is(err, webhook.ErrNotFound) {
+		return response.Error(c, fiber.StatusNotFound, "DELIVERY_NOT_FOUND", "Delivery not found")
+	}
+	if err != nil {
+		h.logger.Error().Err(err).Int64("id", id).Msg("failed to redeliver webhook")
+		return response.Error(c, fiber.StatusInternalServerError, "SAVE_FAILED", "Failed to redeliver webhook")
+	}
+
+	h.logger.Info().Int64("id", id).Msg("webhook redelivery queued")
+	return response.Success(c, fiber.StatusOK, fiber.Map{"id": id}, "Delivery queued for redelivery")
+}
+
+type subscriptionRequest struct {
+	URL     string   `json:"url"`
+	Secret  *string  `json:"secret"`
+	Events  []string `json:"events"`
+	Tokens  []string `json:"tokens"`
+	Format  string   `json:"format"`
+	Enabled *bool    `json:"enabled"`
+}
+
+// apply copies the request onto sub. A missing secret keeps the existing one
+// and a missing enabled flag defaults to true.
+func (r *subscriptionRequest) apply(sub *webhook.Subscription) {
+	sub.URL = r.URL
+	if r.Secret != nil {
+		sub.Secret = *r.Secret
+	}
+	sub.Events = r.Events
+	if sub.Events == nil {
+		sub.Events = []string{}
+	}
+	sub.Tokens = r.Tokens
+	if sub.Tokens == nil {
+		sub.Tokens = []string{}
+	}
+	sub.Format = r.Format
+	sub.Enabled = r.Enabled == nil || *r.Enabled
+}
+
+func (h *Webhook) List(c *fiber.Ctx) error {
+	subs, err := h.dispatcher.Subscriptions()
+	if errors.Is(err, webhook.ErrDisabled) {
+		return response.Error(c, fiber.StatusNotFound, "WEBHOOKS_DISABLED", "Webhook outbox is not configured")
+	}
+	if err != nil {
+		return response.Error(c, fiber.StatusInternalServerError, "FETCH_FAILED", "Failed to retrieve webhook subscriptions")
+	}
+
+	for i := range subs {
+		subs[i] = subs[i].Redacted()
+	}
+	return response.Success(c, fiber.StatusOK, fiber.Map{
+		"subscriptions": subs,
+		"total":         len(subs),
+	}, "Webhook subscriptions retrieved")
+}
+
+func (h *Webhook) Get(c *fiber.Ctx) error {
+	sub, err := h.dispatcher.Subscription(c.Params("id"))
+	if err != nil {
+		return h.subscriptionError(c, err, "FETCH_FAILED", "Failed to retrieve webhook subscription")
+	}
+	return response.Success(c, fiber.StatusOK, sub.Redacted(), "Webhook subscription retrieved")
+}
+
+func (h *Webhook) Create(c *fiber.Ctx) error {
+	var req subscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+
+	var sub webhook.Subscription
+	req.apply(&sub)
+	if err := sub.Validate(); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_SUBSCRIPTION", err.Error())
+	}
+
+	err := h.dispatcher.CreateSubscription(&sub)
+	if err != nil {
+		return h.subscriptionError(c, err, "SAVE_FAILED", "Failed to save webhook subscription")
+	}
+
+	h.logger.Info().Str("subscription", sub.ID).Str("url", sub.URL).Msg("webhook subscription created")
+	return response.Success(c, fiber.StatusCreated, sub.Redacted(), "Webhook subscription created")
+}
+
+func (h *Webhook) Update(c *fiber.Ctx) error {
+	var req subscriptionRequest
+	if err := c.BodyParser(&req); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_REQUEST", "Invalid request body")
+	}
+
+	sub, err := h.dispatcher.Subscription(c.Params("id"))
+	if err != nil {
+		return h.subscriptionError(c, err, "FETCH_FAILED", "Failed to retrieve webhook subscription")
+	}
+	req.apply(sub)
+	if err := sub.Validate(); err != nil {
+		return response.Error(c, fiber.StatusBadRequest, "INVALID_SUBSCRIPTION", err.Error())
+	}
+
+	err = h.dispatcher.UpdateSubscription(sub)
+	if err != nil {
+		return h.subscriptionError(c, err, "SAVE_FAILED", "Failed to save webhook subscription")
+	}
+
+	h.logger.Info().Str("subscription", sub.ID).Msg("webhook subscription updated")
+	return response.Success(c, fiber.StatusOK, sub.Redacted(), "Webhook subscription updated")
+}
+
+func (h *Webhook) Delete(c *fiber.Ctx) error {
+	id := c.Params("id")
+	err := h.dispatcher.DeleteSubscription(id)
+	if err != nil {
+		return h.subscriptionError(c, err, "SAVE_FAILED", "Failed to delete webhook subscription")
+	}
+
+	h.logger.Info().Str("subscription", id).Msg("webhook subscription deleted")
+	return response.Success(c, fiber.StatusOK, fiber.Map{"id": id}, "Webhook subscription deleted")
+}
+
+// subscriptionError writes the error response for a registry error, using
+// code and message for unexpected failures.
+func (h *Webhook) subscriptionError(c *fiber.Ctx, err error, code, message string) error {
+	switch {
+	case errors.Is(err, webhook.ErrDisabled):
+		return response.Error(c, fiber.StatusNotFound, "WEBHOOKS_DISABLED", "Webhook outbox is not configured")
+	case errors.Is(err, webhook.ErrNotFound):
+		return response.Error(c, fiber.StatusNotFound, "SUBSCRIPTION_NOT_FOUND", "Webhook subscription not found")
+	default:
+		h.logger.Error().Err(err).Msg("webhook subscription operation failed")
+		return response.Error(c, fiber.StatusInternalServerError, code, message)
+	}
+}
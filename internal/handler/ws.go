@@ -15,18 +15,18 @@ import (
 type WS struct {
 	hub            *ws.Hub
 	handler        fiber.Handler
-	apiKey         []byte
+	authenticate   ws.Authenticator
 	authTimeout    time.Duration
 	allowedOrigins []string
 	logger         zerolog.Logger
 }
 
-func NewWS(hub *ws.Hub, apiKey string, authTimeout time.Duration, allowedOrigins string, logger zerolog.Logger) *WS {
+func NewWS(hub *ws.Hub, authenticate ws.Authenticator, authTimeout time.Duration, allowedOrigins string, logger zerolog.Logger) *WS {
 	h := &WS{
-		hub:         hub,
-		apiKey:      []byte(apiKey),
-		authTimeout: authTimeout,
-		logger:      logger,
+		hub:          hub,
+		authenticate: authenticate,
+		authTimeout:  authTimeout,
+		logger:       logger,
 	}
 
 	// Parse comma-separated origins, default to wildcard if empty
@@ -82,7 +82,7 @@ func (h *WS) isOriginAllowed(origin string) bool {
 }
 
 func (h *WS) handleConnection(c *websocket.Conn) {
-	client := ws.NewClient(h.hub, c, h.apiKey, h.logger)
+	client := ws.NewClient(h.hub, c, h.authenticate, h.logger)
 	h.hub.Register(client)
 
 	go client.AuthTimeoutPump(h.authTimeout)
@@ -13,6 +13,7 @@ import (
 	"github.com/gofiber/fiber/v2"
 	"github.com/rs/zerolog"
 
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/ws"
 )
 
@@ -25,14 +26,20 @@ type authResponse struct {
 // startTestApp starts a fiber app with the WS handler and returns the ws URL
 func startTestApp(t *testing.T, apiKey string, authTimeout time.Duration, allowedOrigins string) (string, *ws.Hub) {
 	t.Helper()
+	return startTestAppWithAuth(t, ws.KeyAuthenticator(apiKey), authTimeout, allowedOrigins)
+}
+
+func startTestAppWithAuth(t *testing.T, authenticate ws.Authenticator, authTimeout time.Duration, allowedOrigins string) (string, *ws.Hub) {
+	t.Helper()
 
 	logger := zerolog.New(io.Discard)
 	hub := ws.NewHub(logger)
 	go hub.Run()
 
 	app := fiber.New(fiber.Config{DisableStartupMessage: true})
-	wsHandler := NewWS(hub, apiKey, authTimeout, allowedOrigins, logger)
+	wsHandler := NewWS(hub, authenticate, authTimeout, allowedOrigins, logger)
 	app.Get("/ws", wsHandler.Upgrade)
+	app.Get("/events", NewEvents(hub, logger).Stream)
 
 	// Get a free port
 	ln, err := net.Listen("tcp", "127.0.0.1:0")
@@ -249,10 +256,21 @@ func TestIntegration_BroadcastAfterAuth(t *testing.T) {
 		t.Fatalf("auth should succeed: %s", resp.Message)
 	}
 
-	// Give hub time to process
-	time.Sleep(100 * time.Millisecond)
+	// Subscribe to the device
+	sub := map[string]interface{}{"type": "subscribe", "tokens": []string{"60123456789"}}
+	if err := conn.WriteJSON(sub); err != nil {
+		t.Fatalf("write subscribe failed: %v", err)
+	}
+	var subResp map[string]interface{}
+	if err := conn.ReadJSON(&subResp); err != nil {
+		t.Fatalf("read subscribe response failed: %v", err)
+	}
+	if subResp["type"] != "subscribe" || subResp["success"] != true {
+		t.Fatalf("subscribe should succeed: %v", subResp)
+	}
 
-	// Send a broadcast
+	// Send a broadcast for another device (not delivered) and the subscribed one
+	hub.Broadcast("60198765432", "qrcode", "other-device")
 	hub.Broadcast("60123456789", "qrcode", "test-qr-data")
 
 	// Read broadcast
@@ -270,6 +288,115 @@ func TestIntegration_BroadcastAfterAuth(t *testing.T) {
 	if broadcastMsg["event"] != "qrcode" {
 		t.Errorf("expected event 'qrcode', got %v", broadcastMsg["event"])
 	}
+	if broadcastMsg["data"] != "test-qr-data" {
+		t.Errorf("expected subscribed device's data, got %v", broadcastMsg["data"])
+	}
+}
+
+func TestIntegration_ReplayOnSubscribe(t *testing.T) {
+	url, hub := startTestApp(t, "test-api-key", 5*time.Second, "*")
+
+	hub.Broadcast("60123456789", "qrcode", "missed-1")
+	hub.Broadcast("60123456789", "qrcode", "missed-2")
+	time.Sleep(50 * time.Millisecond)
+
+	conn, _, err := fws.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	// lastSeq in auth applies to the first subscribe
+	_ = conn.WriteJSON(map[string]interface{}{"type": "auth", "apiKey": "test-api-key", "lastSeq": 1})
+	var resp authResponse
+	if err := conn.ReadJSON(&resp); err != nil || !resp.Success {
+		t.Fatalf("auth failed: %v %s", err, resp.Message)
+	}
+
+	_ = conn.WriteJSON(map[string]interface{}{"type": "subscribe", "tokens": []string{"60123456789"}})
+
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var replayed map[string]interface{}
+	if err := conn.ReadJSON(&replayed); err != nil {
+		t.Fatalf("read replayed event failed: %v", err)
+	}
+	if replayed["data"] != "missed-2" || replayed["seq"] != float64(2) {
+		t.Errorf("expected replay of seq 2, got %v", replayed)
+	}
+
+	var subResp map[string]interface{}
+	if err := conn.ReadJSON(&subResp); err != nil {
+		t.Fatalf("read subscribe response failed: %v", err)
+	}
+	if subResp["type"] != "subscribe" || subResp["replayed"] != float64(1) {
+		t.Errorf("expected subscribe response with replayed=1, got %v", subResp)
+	}
+}
+
+func TestIntegration_SubscribeRequiresDevicesRead(t *testing.T) {
+	sendOnly := func(string) (ws.Principal, error) {
+		return &apikey.Principal{Key: &apikey.Key{Name: "sender", Scopes: []string{apikey.ScopeMessagesSend}}}, nil
+	}
+	url, hub := startTestAppWithAuth(t, sendOnly, 5*time.Second, "*")
+
+	hub.Broadcast("60123456789", "qrcode", "missed-1")
+	hub.Broadcast("60123456789", "qrcode", "missed-2")
+	time.Sleep(50 * time.Millisecond)
+
+	conn, _, err := fws.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.WriteJSON(map[string]interface{}{"type": "auth", "apiKey": "wag_sender", "lastSeq": 1})
+	var resp authResponse
+	if err := conn.ReadJSON(&resp); err != nil || !resp.Success {
+		t.Fatalf("auth failed: %v %s", err, resp.Message)
+	}
+
+	// Neither subscribe nor its lastSeq replay is allowed without devices:read
+	_ = conn.WriteJSON(map[string]interface{}{"type": "subscribe", "tokens": []string{"60123456789"}, "lastSeq": 1})
+	_ = conn.SetReadDeadline(time.Now().Add(2 * time.Second))
+	var subResp map[string]interface{}
+	if err := conn.ReadJSON(&subResp); err != nil {
+		t.Fatalf("read subscribe response failed: %v", err)
+	}
+	if subResp["type"] != "subscribe" || subResp["success"] != false {
+		t.Fatalf("expected subscribe to be rejected, got %v", subResp)
+	}
+
+	hub.Broadcast("60123456789", "qrcode", "live")
+	_ = conn.SetReadDeadline(time.Now().Add(200 * time.Millisecond))
+	var msg map[string]interface{}
+	if err := conn.ReadJSON(&msg); err == nil {
+		t.Errorf("expected no events without devices:read, got %v", msg)
+	}
+}
+
+func TestIntegration_SubscribeInvalidToken(t *testing.T) {
+	url, _ := startTestApp(t, "test-api-key", 5*time.Second, "*")
+
+	conn, _, err := fws.DefaultDialer.Dial(url, nil)
+	if err != nil {
+		t.Fatalf("dial failed: %v", err)
+	}
+	defer func() { _ = conn.Close() }()
+
+	_ = conn.WriteJSON(map[string]string{"type": "auth", "apiKey": "test-api-key"})
+	var resp authResponse
+	if err := conn.ReadJSON(&resp); err != nil || !resp.Success {
+		t.Fatalf("auth failed: %v %s", err, resp.Message)
+	}
+
+	_ = conn.WriteJSON(map[string]interface{}{"type": "subscribe", "tokens": []string{"not-a-token"}})
+	var subResp map[string]interface{}
+	if err := conn.ReadJSON(&subResp); err != nil {
+		t.Fatalf("read subscribe response failed: %v", err)
+	}
+	if subResp["success"] != false {
+		t.Errorf("expected subscribe to fail for invalid token, got %v", subResp)
+	}
 }
 
 func TestIntegration_OriginRejected(t *testing.T) {
@@ -13,7 +13,7 @@ import (
 func newTestWSHandler(allowedOrigins string) *WS {
 	logger := zerolog.New(io.Discard)
 	hub := ws.NewHub(logger)
-	return NewWS(hub, "test-api-key", 5*time.Second, allowedOrigins, logger)
+	return NewWS(hub, ws.KeyAuthenticator("test-api-key"), 5*time.Second, allowedOrigins, logger)
 }
 
 func TestWS_OriginAllowed(t *testing.T) {
@@ -122,8 +122,11 @@ func TestWS_OriginWhitespaceConfigDefaultsToWildcard(t *testing.T) {
 func TestWS_NewWS_Config(t *testing.T) {
 	h := newTestWSHandler("*")
 
-	if string(h.apiKey) != "test-api-key" {
-		t.Errorf("expected apiKey 'test-api-key', got %q", string(h.apiKey))
+	if _, err := h.authenticate("test-api-key"); err != nil {
+		t.Errorf("expected 'test-api-key' to authenticate, got %v", err)
+	}
+	if _, err := h.authenticate("wrong"); err != ws.ErrInvalidKey {
+		t.Errorf("expected ErrInvalidKey, got %v", err)
 	}
 	if h.authTimeout != 5*time.Second {
 		t.Errorf("expected authTimeout 5s, got %v", h.authTimeout)
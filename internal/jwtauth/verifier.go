@@ -0,0 +1,281 @@
+// Package jwtauth authenticates JWT bearer tokens issued by an external
+// identity provider, mapping their claims to API key scopes, devices and
+// tenant.
+package jwtauth
+
+import (
+	"crypto"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+)
+
+// ErrInvalidToken is returned for tokens that fail verification or carry no
+// usable claims.
+var ErrInvalidToken = errors.New("invalid token")
+
+const (
+	// refreshInterval is how often the JWKS is re-read to pick up rotated keys
+	refreshInterval = time.Hour
+	// minRefreshInterval limits re-reads triggered by unknown key IDs
+	minRefreshInterval = time.Minute
+	// leeway absorbs clock skew when checking exp, nbf and iat
+	leeway = 30 * time.Second
+)
+
+var signingMethods = []string{
+	"RS256", "RS384", "RS512",
+	"PS256", "PS384", "PS512",
+	"ES256", "ES384", "ES512",
+	"EdDSA",
+}
+
+type Config struct {
+	// JWKSFile is a JWK Set on disk. If empty, keys are discovered from Issuer.
+	JWKSFile string
+	// Issuer is checked against the iss claim, and is the OpenID Connect
+	// issuer whose discovery document locates the JWKS.
+	Issuer string
+	// Audience, if set, must be in the aud claim.
+	Audience string
+
+	// Claims holding the scopes (space-separated string or array), allowed
+	// device tokens (array) and tenant (string).
+	ScopeClaim  string
+	TokensClaim string
+	TenantClaim string
+}
+
+// Verifier checks JWTs against the configured signing keys.
+type Verifier struct {
+	cfg    Config
+	parser *jwt.Parser
+	client *http.Client
+	logger zerolog.Logger
+
+	mu      sync.Mutex
+	keys    map[string]crypto.PublicKey
+	fetched time.Time
+	jwksURL string
+}
+
+// New loads the signing keys. A JWKS file must be readable; an issuer that
+// cannot be reached is retried when the first token arrives.
+func New(cfg Config, logger zerolog.Logger) (*Verifier, error) {
+	if cfg.JWKSFile == "" && cfg.Issuer == "" {
+		return nil, fmt.Errorf("a JWKS file or issuer is required")
+	}
+
+	opts := []jwt.ParserOption{
+		jwt.WithValidMethods(signingMethods),
+		jwt.WithExpirationRequired(),
+		jwt.WithLeeway(leeway),
+	}
+	if cfg.Issuer != "" {
+		opts = append(opts, jwt.WithIssuer(cfg.Issuer))
+	}
+	if cfg.Audience != "" {
+		opts = append(opts, jwt.WithAudience(cfg.Audience))
+	}
+
+	v := &Verifier{
+		cfg:    cfg,
+		parser: jwt.NewParser(opts...),
+		client: &http.Client{Timeout: 10 * time.Second},
+		logger: logger.With().Str("component", "jwt").Logger(),
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	if err := v.refresh(); err != nil {
+		if cfg.JWKSFile != "" {
+			return nil, err
+		}
+		v.logger.Warn().Err(err).Str("issuer", cfg.Issuer).Msg("failed to load JWKS, retrying on first token")
+	}
+	return v, nil
+}
+
+// IsJWT reports whether s looks like a compact JWS rather than an API key.
+func IsJWT(s string) bool {
+	return strings.Count(s, ".") == 2
+}
+
+// Verify checks the token's signature, expiry, issuer and audience, and
+// returns the key its claims describe. Errors wrap ErrInvalidToken.
+func (v *Verifier) Verify(raw string) (*apikey.Key, error) {
+	claims := jwt.MapClaims{}
+	if _, err := v.parser.ParseWithClaims(raw, claims, v.keyFunc); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+
+	key, err := v.keyFromClaims(claims)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidToken, err)
+	}
+	return key, nil
+}
+
+// keyFromClaims maps the token's claims to a key. Scopes the gateway does not
+// know, such as openid or profile, are ignored.
+func (v *Verifier) keyFromClaims(claims jwt.MapClaims) (*apikey.Key, error) {
+	sub, _ := claims.GetSubject()
+	if sub == "" {
+		sub = "jwt"
+	}
+	if len(sub) > 100 {
+		sub = sub[:100]
+	}
+
+	key := &apikey.Key{Name: sub, Scopes: []string{}, Tokens: stringList(claims[v.cfg.TokensClaim])}
+	for _, s := range stringList(claims[v.cfg.ScopeClaim]) {
+		for _, known := range apikey.Scopes {
+			if s == known {
+				key.Scopes = append(key.Scopes, s)
+			}
+		}
+	}
+	if key.Tokens == nil {
+		key.Tokens = []string{}
+	}
+	if tenant, ok := claims[v.cfg.TenantClaim].(string); ok {
+		key.Tenant = tenant
+	}
+
+	if err := key.Validate(); err != nil {
+		return nil, err
+	}
+	return key, nil
+}
+
+// stringList reads a claim that is either a space-separated string or an
+// array of strings.
+func stringList(claim any) []string {
+	switch c := claim.(type) {
+	case string:
+		return strings.Fields(c)
+	case []any:
+		list := make([]string, 0, len(c))
+		for _, item := range c {
+			if s, ok := item.(string); ok {
+				list = append(list, s)
+			}
+		}
+		return list
+	}
+	return nil
+}
+
+func (v *Verifier) keyFunc(token *jwt.Token) (any, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	if time.Since(v.fetched) > refreshInterval {
+		v.tryRefresh()
+	}
+	if key, ok := v.lookup(kid); ok {
+		return key, nil
+	}
+	// The provider may have rotated keys since the last fetch
+	if time.Since(v.fetched) > minRefreshInterval {
+		v.tryRefresh()
+		if key, ok := v.lookup(kid); ok {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("unknown signing key %q", kid)
+}
+
+// lookup finds the key for kid. Tokens without a kid are accepted when the
+// JWKS holds a single key. Callers hold mu.
+func (v *Verifier) lookup(kid string) (crypto.PublicKey, bool) {
+	if kid == "" && len(v.keys) == 1 {
+		for _, key := range v.keys {
+			return key, true
+		}
+	}
+	key, ok := v.keys[kid]
+	return key, ok
+}
+
+// tryRefresh re-reads the JWKS, keeping the current keys if that fails.
+// Callers hold mu.
+func (v *Verifier) tryRefresh() {
+	if err := v.refresh(); err != nil {
+		v.logger.Warn().Err(err).Msg("failed to refresh JWKS")
+	}
+}
+
+// refresh reads the JWKS from the file or the issuer. Callers hold mu.
+func (v *Verifier) refresh() error {
+	// Failed attempts also count, so an unreachable issuer is not hammered
+	v.fetched = time.Now()
+
+	var (
+		data []byte
+		err  error
+	)
+	if v.cfg.JWKSFile != "" {
+		data, err = os.ReadFile(v.cfg.JWKSFile)
+	} else {
+		data, err = v.fetchIssuerJWKS()
+	}
+	if err != nil {
+		return fmt.Errorf("failed to read JWKS: %w", err)
+	}
+
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+	v.keys = keys
+	return nil
+}
+
+// fetchIssuerJWKS downloads the JWKS named by the issuer's OpenID Connect
+// discovery document. Callers hold mu.
+func (v *Verifier) fetchIssuerJWKS() ([]byte, error) {
+	if v.jwksURL == "" {
+		data, err := v.get(strings.TrimSuffix(v.cfg.Issuer, "/") + "/.well-known/openid-configuration")
+		if err != nil {
+			return nil, err
+		}
+		var discovery struct {
+			JWKSURI string `json:"jwks_uri"`
+		}
+		if err := json.Unmarshal(data, &discovery); err != nil {
+			return nil, fmt.Errorf("invalid discovery document: %w", err)
+		}
+		if discovery.JWKSURI == "" {
+			return nil, fmt.Errorf("discovery document has no jwks_uri")
+		}
+		v.jwksURL = discovery.JWKSURI
+	}
+	return v.get(v.jwksURL)
+}
+
+func (v *Verifier) get(url string) ([]byte, error) {
+	resp, err := v.client.Get(url)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = resp.Body.Close() }()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("GET %s: %s", url, resp.Status)
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+}
@@ -0,0 +1,201 @@
+package jwtauth
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"io"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+)
+
+func b64(b []byte) string {
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+func rsaJWK(kid string, key *rsa.PrivateKey) map[string]string {
+	return map[string]string{
+		"kty": "RSA", "kid": kid, "use": "sig",
+		"n": b64(key.N.Bytes()),
+		"e": b64(big.NewInt(int64(key.E)).Bytes()),
+	}
+}
+
+func writeJWKS(t *testing.T, keys ...map[string]string) string {
+	t.Helper()
+	data, err := json.Marshal(map[string]any{"keys": keys})
+	if err != nil {
+		t.Fatalf("marshal JWKS: %v", err)
+	}
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write JWKS: %v", err)
+	}
+	return path
+}
+
+func sign(t *testing.T, method jwt.SigningMethod, kid string, key any, claims jwt.MapClaims) string {
+	t.Helper()
+	token := jwt.NewWithClaims(method, claims)
+	token.Header["kid"] = kid
+	s, err := token.SignedString(key)
+	if err != nil {
+		t.Fatalf("sign: %v", err)
+	}
+	return s
+}
+
+func testConfig(jwksFile string) Config {
+	return Config{
+		JWKSFile:    jwksFile,
+		Issuer:      "https://sso.example.com",
+		Audience:    "wa-gateway",
+		ScopeClaim:  "scope",
+		TokensClaim: "devices",
+		TenantClaim: "tenant",
+	}
+}
+
+func TestVerifier_JWKSFile(t *testing.T) {
+	rsaKey, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	ecKey, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate EC key: %v", err)
+	}
+	other, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+	ecJWK := map[string]string{"kty": "EC", "kid": "ec", "crv": "P-256", "x": b64(ecKey.X.FillBytes(make([]byte, 32))), "y": b64(ecKey.Y.FillBytes(make([]byte, 32)))}
+
+	v, err := New(testConfig(writeJWKS(t, rsaJWK("rsa", rsaKey), ecJWK)), zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	claims := func(extra jwt.MapClaims) jwt.MapClaims {
+		c := jwt.MapClaims{
+			"iss":     "https://sso.example.com",
+			"aud":     "wa-gateway",
+			"sub":     "alice@example.com",
+			"exp":     time.Now().Add(time.Hour).Unix(),
+			"scope":   "openid profile devices:read messages:send",
+			"devices": []string{"60123456789"},
+		}
+		for k, val := range extra {
+			c[k] = val
+		}
+		return c
+	}
+
+	key, err := v.Verify(sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(nil)))
+	if err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+	if key.Name != "alice@example.com" || len(key.Scopes) != 2 || !key.HasScope(apikey.ScopeMessagesSend) || key.HasScope(apikey.ScopeAdmin) {
+		t.Errorf("unexpected key: %+v", key)
+	}
+	if !key.AllowsToken("60123456789") || key.AllowsToken("60198765432") {
+		t.Errorf("unexpected devices: %v", key.Tokens)
+	}
+
+	key, err = v.Verify(sign(t, jwt.SigningMethodES256, "ec", ecKey, claims(jwt.MapClaims{"scope": []string{"devices:write"}, "tenant": "acme"})))
+	if err != nil {
+		t.Fatalf("expected valid EC token, got %v", err)
+	}
+	if key.Tenant != "acme" || !key.HasScope(apikey.ScopeDevicesWrite) {
+		t.Errorf("unexpected key: %+v", key)
+	}
+
+	rejected := []struct {
+		name  string
+		token string
+	}{
+		{"expired", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{"exp": time.Now().Add(-time.Hour).Unix()}))},
+		{"no expiry", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{"exp": nil}))},
+		{"wrong issuer", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{"iss": "https://evil.example.com"}))},
+		{"wrong audience", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{"aud": "other"}))},
+		{"unknown key", sign(t, jwt.SigningMethodRS256, "other", other, claims(nil))},
+		{"wrong signature", sign(t, jwt.SigningMethodRS256, "rsa", other, claims(nil))},
+		{"hmac", sign(t, jwt.SigningMethodHS256, "rsa", []byte("secret"), claims(nil))},
+		{"no gateway scopes", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{"scope": "openid"}))},
+		{"tenant admin", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{"scope": "admin", "tenant": "acme"}))},
+		{"invalid device", sign(t, jwt.SigningMethodRS256, "rsa", rsaKey, claims(jwt.MapClaims{"devices": []string{"abc"}}))},
+		{"garbage", "a.b.c"},
+	}
+	for _, tt := range rejected {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := v.Verify(tt.token); !errors.Is(err, ErrInvalidToken) {
+				t.Errorf("expected ErrInvalidToken, got %v", err)
+			}
+		})
+	}
+}
+
+func TestVerifier_IssuerDiscovery(t *testing.T) {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	if err != nil {
+		t.Fatalf("generate RSA key: %v", err)
+	}
+
+	var srv *httptest.Server
+	srv = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/.well-known/openid-configuration":
+			_ = json.NewEncoder(w).Encode(map[string]string{"issuer": srv.URL, "jwks_uri": srv.URL + "/keys"})
+		case "/keys":
+			_ = json.NewEncoder(w).Encode(map[string]any{"keys": []map[string]string{rsaJWK("k1", key)}})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer srv.Close()
+
+	cfg := testConfig("")
+	cfg.Issuer = srv.URL + "/"
+	cfg.Audience = ""
+	v, err := New(cfg, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+
+	token := sign(t, jwt.SigningMethodRS256, "k1", key, jwt.MapClaims{
+		"iss":   srv.URL + "/",
+		"sub":   "dashboard",
+		"exp":   time.Now().Add(time.Hour).Unix(),
+		"scope": "admin",
+	})
+	got, err := v.Verify(token)
+	if err != nil {
+		t.Fatalf("expected valid token, got %v", err)
+	}
+	if !got.HasScope(apikey.ScopeAdmin) || !got.AllowsToken("60123456789") {
+		t.Errorf("unexpected key: %+v", got)
+	}
+}
+
+func TestNew_RequiresKeySource(t *testing.T) {
+	if _, err := New(Config{}, zerolog.New(io.Discard)); err == nil {
+		t.Error("expected error without a JWKS file or issuer")
+	}
+	if _, err := New(Config{JWKSFile: filepath.Join(t.TempDir(), "missing.json")}, zerolog.New(io.Discard)); err == nil {
+		t.Error("expected error for a missing JWKS file")
+	}
+}
@@ -0,0 +1,77 @@
+package middleware
+
+import (
+	"encoding/json"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/audit"
+)
+
+// Audit records requests to the audit log.
+type Audit struct {
+	store  *audit.Store
+	logger zerolog.Logger
+}
+
+func NewAudit(store *audit.Store, logger zerolog.Logger) *Audit {
+	return &Audit{store: store, logger: logger}
+}
+
+// Record logs the request as action once the handler has run, whether or not
+// it succeeded. Put it before Scope so rejected attempts are recorded too.
+// It must run after Require.
+func (a *Audit) Record(action string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		err := c.Next()
+
+		status := c.Response().StatusCode()
+		if err != nil {
+			status = fiber.StatusInternalServerError
+			if e, ok := err.(*fiber.Error); ok {
+				status = e.Code
+			}
+		}
+
+		p := PrincipalFrom(c)
+		entry := &audit.Entry{
+			Action:  action,
+			Actor:   p.Name,
+			KeyID:   p.ID,
+			IP:      c.IP(),
+			Source:  audit.SourceAPI,
+			Target:  auditTarget(c),
+			Success: status < fiber.StatusBadRequest,
+			Status:  status,
+			Detail:  c.Method() + " " + c.Path(),
+		}
+		if recErr := a.store.Record(entry); recErr != nil {
+			a.logger.Error().Err(recErr).Str("action", action).Str("actor", entry.Actor).Msg("failed to record audit entry")
+		}
+		return err
+	}
+}
+
+// auditTarget is the device token or resource the request acts on: the
+// :token or :id route parameter, or the token or name in the JSON body.
+func auditTarget(c *fiber.Ctx) string {
+	if token := c.Params("token"); token != "" {
+		return token
+	}
+	if id := c.Params("id"); id != "" {
+		return id
+	}
+
+	var body struct {
+		Token string `json:"token"`
+		Name  string `json:"name"`
+	}
+	if json.Unmarshal(c.Body(), &body) != nil {
+		return ""
+	}
+	if body.Token != "" {
+		return body.Token
+	}
+	return body.Name
+}
@@ -2,36 +2,121 @@ package middleware
 
 import (
 	"crypto/subtle"
+	"errors"
 	"strings"
 
 	"github.com/gofiber/fiber/v2"
 
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/jwtauth"
 	"github.com/AsyrafHussin/wa-gateway-go/pkg/response"
 )
 
+// principalLocal is the fiber.Ctx local holding the authenticated
+// *apikey.Principal.
+const principalLocal = "principal"
+
+// master is the principal for API_KEY, and for requests without Auth
+var master = &apikey.Principal{Key: apikey.Master}
+
 type Auth struct {
 	apiKey []byte
+	keys   *apikey.Store
+	jwt    *jwtauth.Verifier
 }
 
-func NewAuth(apiKey string) *Auth {
-	return &Auth{apiKey: []byte(apiKey)}
+// NewAuth accepts the master API key, the named keys in keys and JWTs checked
+// by verifier. keys and verifier may be nil.
+func NewAuth(apiKey string, keys *apikey.Store, verifier *jwtauth.Verifier) *Auth {
+	return &Auth{apiKey: []byte(apiKey), keys: keys, jwt: verifier}
 }
 
 func (a *Auth) Require() fiber.Handler {
+	return a.require(false)
+}
+
+// RequireAllowQuery is Require that also accepts the key as an apiKey query
+// parameter, for clients such as EventSource that cannot set headers.
+func (a *Auth) RequireAllowQuery() fiber.Handler {
+	return a.require(true)
+}
+
+func (a *Auth) require(allowQuery bool) fiber.Handler {
 	return func(c *fiber.Ctx) error {
 		key := extractAPIKey(c)
+		if key == "" && allowQuery {
+			key = c.Query("apiKey")
+		}
 		if key == "" {
 			return response.Error(c, fiber.StatusUnauthorized, "UNAUTHORIZED", "Missing API key")
 		}
 
-		if subtle.ConstantTimeCompare([]byte(key), a.apiKey) != 1 {
+		p, err := a.Authenticate(key)
+		if errors.Is(err, apikey.ErrNotFound) {
 			return response.Error(c, fiber.StatusUnauthorized, "UNAUTHORIZED", "Invalid API key")
 		}
+		if errors.Is(err, jwtauth.ErrInvalidToken) {
+			return response.Error(c, fiber.StatusUnauthorized, "UNAUTHORIZED", "Invalid bearer token")
+		}
+		if err != nil {
+			return response.Error(c, fiber.StatusInternalServerError, "INTERNAL_ERROR", "Failed to verify API key")
+		}
 
+		c.Locals(principalLocal, p)
 		return c.Next()
 	}
 }
 
+// Authenticate resolves an API key or JWT to a principal, returning
+// apikey.ErrNotFound for unknown keys and jwtauth.ErrInvalidToken for
+// rejected JWTs. The WebSocket uses it for its first-message auth.
+func (a *Auth) Authenticate(key string) (*apikey.Principal, error) {
+	if subtle.ConstantTimeCompare([]byte(key), a.apiKey) == 1 {
+		return master, nil
+	}
+	if a.jwt != nil && jwtauth.IsJWT(key) {
+		k, err := a.jwt.Verify(key)
+		if err != nil {
+			return nil, err
+		}
+		return a.keys.Principal(k), nil
+	}
+	if a.keys == nil {
+		return nil, apikey.ErrNotFound
+	}
+	return a.keys.Authenticate(key)
+}
+
+// Scope rejects requests whose key lacks scope, or that name a device in the
+// :token route parameter the key may not use (outside its tokens or owned
+// by another tenant). It must run after Require.
+func Scope(scope string) fiber.Handler {
+	return func(c *fiber.Ctx) error {
+		p := PrincipalFrom(c)
+		if !p.HasScope(scope) {
+			return response.Error(c, fiber.StatusForbidden, "INSUFFICIENT_SCOPE", "API key lacks the "+scope+" scope")
+		}
+		if token := c.Params("token"); token != "" && !p.CanUse(token) {
+			return TokenNotAllowed(c)
+		}
+		return c.Next()
+	}
+}
+
+// PrincipalFrom returns the principal that authenticated the request. Routes
+// without Auth are treated as using the master key.
+func PrincipalFrom(c *fiber.Ctx) *apikey.Principal {
+	if p, ok := c.Locals(principalLocal).(*apikey.Principal); ok {
+		return p
+	}
+	return master
+}
+
+// TokenNotAllowed writes the error for a device the key may not use.
+func TokenNotAllowed(c *fiber.Ctx) error {
+	return response.Error(c, fiber.StatusForbidden, "TOKEN_NOT_ALLOWED", "API key is not allowed to use this device")
+}
+
 func extractAPIKey(c *fiber.Ctx) string {
 	// Check Authorization: Bearer <key>
 	if auth := c.Get("Authorization"); auth != "" {
@@ -0,0 +1,137 @@
+package optout
+
+import (
+	"database/sql"
+	"errors"
+	"strings"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type OptOut struct {
+	Phone     string `json:"phone"`
+	Source    string `json:"source"`
+	Keyword   string `json:"keyword,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(dbPath string) (*Store, error) {
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS optouts (
+			phone      TEXT PRIMARY KEY,
+			source     TEXT DEFAULT 'keyword',
+			keyword    TEXT DEFAULT '',
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Add records an opt-out. It reports false if the phone had already opted out.
+func (s *Store) Add(phone, source, keyword string) (bool, error) {
+	now := time.Now().UTC().Format(time.RFC3339)
+	res, err := s.db.Exec(`
+		INSERT INTO optouts (phone, source, keyword, created_at) VALUES (?, ?, ?, ?)
+		ON CONFLICT(phone) DO NOTHING
+	`, phone, source, keyword, now)
+	if err != nil {
+		return false, err
+	}
+	n, err := res.RowsAffected()
+	return n > 0, err
+}
+
+func (s *Store) Remove(phone string) error {
+	res, err := s.db.Exec("DELETE FROM optouts WHERE phone = ?", phone)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Store) Has(phone string) (bool, error) {
+	var exists bool
+	err := s.db.QueryRow("SELECT EXISTS(SELECT 1 FROM optouts WHERE phone = ?)", phone).Scan(&exists)
+	return exists, err
+}
+
+func (s *Store) List(limit, offset int) ([]OptOut, int, error) {
+	var total int
+	if err := s.db.QueryRow("SELECT COUNT(*) FROM optouts").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := s.db.Query(
+		"SELECT phone, source, keyword, created_at FROM optouts ORDER BY created_at DESC LIMIT ? OFFSET ?",
+		limit, offset,
+	)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	optouts := []OptOut{}
+	for rows.Next() {
+		var o OptOut
+		if err := rows.Scan(&o.Phone, &o.Source, &o.Keyword, &o.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		optouts = append(optouts, o)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return optouts, total, nil
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+// ParseKeywords splits a comma-separated keyword list, dropping empty entries.
+func ParseKeywords(list string) []string {
+	var keywords []string
+	for _, k := range strings.Split(list, ",") {
+		if k = strings.TrimSpace(k); k != "" {
+			keywords = append(keywords, k)
+		}
+	}
+	return keywords
+}
+
+// MatchKeyword returns the keyword the whole message equals (ignoring case,
+// surrounding whitespace and trailing punctuation), or "" if none match.
+func MatchKeyword(text string, keywords []string) string {
+	text = strings.TrimRight(strings.TrimSpace(text), ".!")
+	for _, k := range keywords {
+		if strings.EqualFold(text, k) {
+			return k
+		}
+	}
+	return ""
+}
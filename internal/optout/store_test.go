@@ -0,0 +1,69 @@
+package optout
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestStore_AddHasRemove(t *testing.T) {
+	s, err := NewStore(filepath.Join(t.TempDir(), "optouts.db"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	added, err := s.Add("60123456789", "keyword", "STOP")
+	if err != nil || !added {
+		t.Fatalf("expected first add to succeed, got added=%v err=%v", added, err)
+	}
+	added, err = s.Add("60123456789", "api", "")
+	if err != nil || added {
+		t.Fatalf("expected duplicate add to be ignored, got added=%v err=%v", added, err)
+	}
+
+	has, err := s.Has("60123456789")
+	if err != nil || !has {
+		t.Fatalf("expected phone to be opted out, got has=%v err=%v", has, err)
+	}
+
+	list, total, err := s.List(10, 0)
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if total != 1 || len(list) != 1 || list[0].Source != "keyword" || list[0].Keyword != "STOP" {
+		t.Fatalf("unexpected list: total=%d %+v", total, list)
+	}
+
+	if err := s.Remove("60123456789"); err != nil {
+		t.Fatalf("remove failed: %v", err)
+	}
+	if err := s.Remove("60123456789"); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound on second remove, got %v", err)
+	}
+	if has, _ := s.Has("60123456789"); has {
+		t.Error("expected phone to be removed")
+	}
+}
+
+func TestMatchKeyword(t *testing.T) {
+	keywords := ParseKeywords(" STOP, unsubscribe ,,")
+	if len(keywords) != 2 {
+		t.Fatalf("expected 2 keywords, got %v", keywords)
+	}
+
+	tests := []struct {
+		text string
+		want string
+	}{
+		{"stop", "STOP"},
+		{"  Stop! ", "STOP"},
+		{"UNSUBSCRIBE.", "unsubscribe"},
+		{"please stop", ""},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := MatchKeyword(tt.text, keywords); got != tt.want {
+			t.Errorf("text %q: expected %q, got %q", tt.text, tt.want, got)
+		}
+	}
+}
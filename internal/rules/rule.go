@@ -0,0 +1,240 @@
+package rules
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/schedule"
+)
+
+const (
+	MatchKeyword      = "keyword"
+	MatchRegex        = "regex"
+	MatchFirstContact = "first_contact"
+
+	ReplyText     = "text"
+	ReplyTemplate = "template"
+
+	DuringOpen   = "open"
+	DuringClosed = "closed"
+)
+
+var templateNameRegex = regexp.MustCompile(`^[a-z0-9_-]{1,64}$`)
+
+// Rule is an auto-reply rule evaluated against inbound messages.
+type Rule struct {
+	ID            string      `json:"id"`
+	Name          string      `json:"name"`
+	Match         string      `json:"match"`
+	Pattern       string      `json:"pattern,omitempty"`
+	CaseSensitive bool        `json:"caseSensitive"`
+	Schedule      *TimeWindow `json:"schedule,omitempty"`
+	During        string      `json:"during,omitempty"`
+	Reply         Reply       `json:"reply"`
+	Priority      int         `json:"priority"`
+	Enabled       bool        `json:"enabled"`
+	CreatedAt     string      `json:"createdAt"`
+	UpdatedAt     string      `json:"updatedAt"`
+}
+
+type Reply struct {
+	Type     string `json:"type"`
+	Text     string `json:"text,omitempty"`
+	Template string `json:"template,omitempty"`
+}
+
+// TimeWindow restricts a rule to certain days and a time-of-day range.
+// Start after End means the window spans midnight (e.g. 22:00-06:00).
+type TimeWindow struct {
+	Timezone string   `json:"timezone,omitempty"`
+	Days     []string `json:"days,omitempty"`
+	Start    string   `json:"start"`
+	End      string   `json:"end"`
+}
+
+type Template struct {
+	Name      string `json:"name"`
+	Body      string `json:"body"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+// Inbound describes the message a rule is evaluated against. BusinessOpen is
+// the device's business-hours state (true when no schedule is configured).
+type Inbound struct {
+	Text         string
+	FirstContact bool
+	BusinessOpen bool
+	Time         time.Time
+}
+
+func (r *Rule) Validate() error {
+	if strings.TrimSpace(r.Name) == "" {
+		return fmt.Errorf("name is required")
+	}
+
+	switch r.Match {
+	case MatchKeyword:
+		if strings.TrimSpace(r.Pattern) == "" {
+			return fmt.Errorf("pattern is required for keyword rules")
+		}
+	case MatchRegex:
+		if r.Pattern == "" {
+			return fmt.Errorf("pattern is required for regex rules")
+		}
+		if _, err := r.compile(); err != nil {
+			return fmt.Errorf("invalid regex: %w", err)
+		}
+	case MatchFirstContact:
+	default:
+		return fmt.Errorf("match must be 'keyword', 'regex' or 'first_contact'")
+	}
+
+	switch r.Reply.Type {
+	case ReplyText:
+		if strings.TrimSpace(r.Reply.Text) == "" {
+			return fmt.Errorf("reply text is required")
+		}
+	case ReplyTemplate:
+		if !ValidTemplateName(r.Reply.Template) {
+			return fmt.Errorf("reply template name is invalid")
+		}
+	default:
+		return fmt.Errorf("reply type must be 'text' or 'template'")
+	}
+
+	if r.Schedule != nil {
+		if err := r.Schedule.Validate(); err != nil {
+			return fmt.Errorf("invalid schedule: %w", err)
+		}
+	}
+
+	if r.During != "" && r.During != DuringOpen && r.During != DuringClosed {
+		return fmt.Errorf("during must be 'open' or 'closed'")
+	}
+	return nil
+}
+
+// Matches reports whether the rule applies to the inbound message.
+func (r *Rule) Matches(in Inbound) bool {
+	if !r.Enabled {
+		return false
+	}
+	if r.Schedule != nil && !r.Schedule.Contains(in.Time) {
+		return false
+	}
+	if (r.During == DuringOpen && !in.BusinessOpen) || (r.During == DuringClosed && in.BusinessOpen) {
+		return false
+	}
+
+	switch r.Match {
+	case MatchKeyword:
+		text := strings.TrimSpace(in.Text)
+		if text == "" {
+			return false
+		}
+		pattern := strings.TrimSpace(r.Pattern)
+		if r.CaseSensitive {
+			return text == pattern
+		}
+		return strings.EqualFold(text, pattern)
+	case MatchRegex:
+		re, err := r.compile()
+		if err != nil || in.Text == "" {
+			return false
+		}
+		return re.MatchString(in.Text)
+	case MatchFirstContact:
+		return in.FirstContact
+	}
+	return false
+}
+
+func (r *Rule) compile() (*regexp.Regexp, error) {
+	pattern := r.Pattern
+	if !r.CaseSensitive {
+		pattern = "(?i)" + pattern
+	}
+	return regexp.Compile(pattern)
+}
+
+// Evaluate returns the first matching rule. Rules must already be sorted by priority.
+func Evaluate(rules []Rule, in Inbound) *Rule {
+	for i := range rules {
+		if rules[i].Matches(in) {
+			return &rules[i]
+		}
+	}
+	return nil
+}
+
+func (w *TimeWindow) Validate() error {
+	if _, err := time.LoadLocation(w.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", w.Timezone)
+	}
+	if _, err := schedule.ParseClock(w.Start); err != nil {
+		return err
+	}
+	if _, err := schedule.ParseClock(w.End); err != nil {
+		return err
+	}
+	for _, d := range w.Days {
+		if _, err := schedule.ParseWeekday(d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Contains reports whether t falls inside the window, evaluated in the window's timezone.
+func (w *TimeWindow) Contains(t time.Time) bool {
+	loc, err := time.LoadLocation(w.Timezone)
+	if err != nil {
+		return false
+	}
+	start, err := schedule.ParseClock(w.Start)
+	if err != nil {
+		return false
+	}
+	end, err := schedule.ParseClock(w.End)
+	if err != nil {
+		return false
+	}
+
+	local := t.In(loc)
+	minute := local.Hour()*60 + local.Minute()
+	day := local.Weekday()
+
+	// For overnight windows, the early-morning part belongs to the previous day's window
+	if start > end && minute < end {
+		day = (day + 6) % 7
+	}
+	if len(w.Days) > 0 && !w.hasDay(day) {
+		return false
+	}
+
+	if start <= end {
+		return minute >= start && minute < end
+	}
+	return minute >= start || minute < end
+}
+
+func (w *TimeWindow) hasDay(day time.Weekday) bool {
+	for _, d := range w.Days {
+		if wd, err := schedule.ParseWeekday(d); err == nil && wd == day {
+			return true
+		}
+	}
+	return false
+}
+
+// ValidTemplateName reports whether name can be used as a template identifier.
+func ValidTemplateName(name string) bool {
+	return templateNameRegex.MatchString(name)
+}
+
+// Render substitutes {{name}} and {{phone}} placeholders in a template body.
+func (t *Template) Render(name, phone string) string {
+	return strings.NewReplacer("{{name}}", name, "{{phone}}", phone).Replace(t.Body)
+}
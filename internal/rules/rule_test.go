@@ -0,0 +1,173 @@
+package rules
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestRule_KeywordMatch(t *testing.T) {
+	r := Rule{Name: "price", Match: MatchKeyword, Pattern: "price", Enabled: true}
+
+	tests := []struct {
+		text  string
+		match bool
+	}{
+		{"price", true},
+		{"  PRICE ", true},
+		{"price list", false},
+		{"", false},
+	}
+	for _, tt := range tests {
+		if got := r.Matches(Inbound{Text: tt.text}); got != tt.match {
+			t.Errorf("text %q: expected match=%v, got %v", tt.text, tt.match, got)
+		}
+	}
+
+	r.CaseSensitive = true
+	if r.Matches(Inbound{Text: "PRICE"}) {
+		t.Error("expected case-sensitive keyword not to match different case")
+	}
+}
+
+func TestRule_RegexMatch(t *testing.T) {
+	r := Rule{Name: "hours", Match: MatchRegex, Pattern: `\b(open|hours)\b`, Enabled: true}
+
+	if !r.Matches(Inbound{Text: "What are your Opening HOURS?"}) {
+		t.Error("expected case-insensitive regex match")
+	}
+	if r.Matches(Inbound{Text: "hello"}) {
+		t.Error("expected no match")
+	}
+}
+
+func TestRule_FirstContact(t *testing.T) {
+	r := Rule{Name: "welcome", Match: MatchFirstContact, Enabled: true}
+
+	if !r.Matches(Inbound{FirstContact: true}) {
+		t.Error("expected first-contact rule to match new contact")
+	}
+	if r.Matches(Inbound{Text: "hi", FirstContact: false}) {
+		t.Error("expected first-contact rule not to match known contact")
+	}
+}
+
+func TestRule_Disabled(t *testing.T) {
+	r := Rule{Name: "price", Match: MatchKeyword, Pattern: "price", Enabled: false}
+	if r.Matches(Inbound{Text: "price"}) {
+		t.Error("disabled rule should never match")
+	}
+}
+
+func TestRule_Validate(t *testing.T) {
+	valid := Rule{Name: "r", Match: MatchKeyword, Pattern: "hi", Reply: Reply{Type: ReplyText, Text: "hello"}}
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid rule, got %v", err)
+	}
+
+	invalid := []Rule{
+		{Match: MatchKeyword, Pattern: "hi", Reply: Reply{Type: ReplyText, Text: "x"}},
+		{Name: "r", Match: "fuzzy", Reply: Reply{Type: ReplyText, Text: "x"}},
+		{Name: "r", Match: MatchKeyword, Reply: Reply{Type: ReplyText, Text: "x"}},
+		{Name: "r", Match: MatchRegex, Pattern: "(", Reply: Reply{Type: ReplyText, Text: "x"}},
+		{Name: "r", Match: MatchFirstContact, Reply: Reply{Type: ReplyText}},
+		{Name: "r", Match: MatchFirstContact, Reply: Reply{Type: ReplyTemplate, Template: "Bad Name"}},
+		{Name: "r", Match: MatchFirstContact, Reply: Reply{Type: ReplyText, Text: "x"}, Schedule: &TimeWindow{Start: "9am", End: "17:00"}},
+		{Name: "r", Match: MatchFirstContact, Reply: Reply{Type: ReplyText, Text: "x"}, Schedule: &TimeWindow{Timezone: "Mars/Base", Start: "09:00", End: "17:00"}},
+	}
+	for i, r := range invalid {
+		if err := r.Validate(); err == nil {
+			t.Errorf("rule %d: expected validation error", i)
+		}
+	}
+}
+
+func TestTimeWindow_Contains(t *testing.T) {
+	w := TimeWindow{Timezone: "Asia/Kuala_Lumpur", Days: []string{"mon", "tue", "wed", "thu", "fri"}, Start: "09:00", End: "17:00"}
+
+	// 2026-02-16 is a Monday
+	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
+	tests := []struct {
+		name string
+		at   time.Time
+		in   bool
+	}{
+		{"monday morning", time.Date(2026, 2, 16, 9, 0, 0, 0, loc), true},
+		{"monday end exclusive", time.Date(2026, 2, 16, 17, 0, 0, 0, loc), false},
+		{"monday early", time.Date(2026, 2, 16, 8, 59, 0, 0, loc), false},
+		{"sunday", time.Date(2026, 2, 15, 12, 0, 0, 0, loc), false},
+		{"utc input converted", time.Date(2026, 2, 16, 2, 0, 0, 0, time.UTC), true},
+	}
+	for _, tt := range tests {
+		if got := w.Contains(tt.at); got != tt.in {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.in, got)
+		}
+	}
+}
+
+func TestTimeWindow_Overnight(t *testing.T) {
+	w := TimeWindow{Days: []string{"fri"}, Start: "22:00", End: "06:00"}
+
+	// 2026-02-20 is a Friday
+	if !w.Contains(time.Date(2026, 2, 20, 23, 0, 0, 0, time.UTC)) {
+		t.Error("expected friday 23:00 to be inside")
+	}
+	if !w.Contains(time.Date(2026, 2, 21, 5, 0, 0, 0, time.UTC)) {
+		t.Error("expected saturday 05:00 to belong to friday's window")
+	}
+	if w.Contains(time.Date(2026, 2, 20, 5, 0, 0, 0, time.UTC)) {
+		t.Error("expected friday 05:00 (thursday's window) to be outside")
+	}
+}
+
+func TestEvaluate_FirstMatchWins(t *testing.T) {
+	list := []Rule{
+		{ID: "a", Match: MatchKeyword, Pattern: "other", Enabled: true},
+		{ID: "b", Match: MatchRegex, Pattern: "hi", Enabled: true},
+		{ID: "c", Match: MatchKeyword, Pattern: "hi", Enabled: true},
+	}
+
+	r := Evaluate(list, Inbound{Text: "hi"})
+	if r == nil || r.ID != "b" {
+		t.Fatalf("expected rule b, got %+v", r)
+	}
+	if Evaluate(list, Inbound{Text: "bye"}) != nil {
+		t.Error("expected no match")
+	}
+}
+
+func TestTemplate_Render(t *testing.T) {
+	tmpl := Template{Body: "Hi {{name}}, we'll call {{phone}} soon."}
+	got := tmpl.Render("Ali", "60198765432")
+	if got != "Hi Ali, we'll call 60198765432 soon." {
+		t.Errorf("unexpected render: %q", got)
+	}
+}
+
+func TestStore_RulesOrderedByPriority(t *testing.T) {
+	s, err := NewStore(filepath.Join(t.TempDir(), "rules.db"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	low := Rule{Name: "low", Priority: 1}
+	high := Rule{Name: "high", Priority: 10}
+	_ = s.Create(&low)
+	_ = s.Create(&high)
+
+	list, err := s.List()
+	if err != nil {
+		t.Fatalf("list failed: %v", err)
+	}
+	if len(list) != 2 || list[0].Name != "high" {
+		t.Fatalf("expected high-priority rule first, got %+v", list)
+	}
+
+	if err := s.Delete(low.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if err := s.Delete(low.ID); err != ErrNotFound {
+		t.Errorf("expected ErrNotFound on second delete, got %v", err)
+	}
+}
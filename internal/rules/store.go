@@ -0,0 +1,190 @@
+package rules
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	_ "modernc.org/sqlite"
+)
+
+var ErrNotFound = errors.New("not found")
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(dbPath string) (*Store, error) {
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS rules (
+			id         TEXT PRIMARY KEY,
+			priority   INTEGER DEFAULT 0,
+			data       TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS templates (
+			name       TEXT PRIMARY KEY,
+			body       TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+	`)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// List returns all rules ordered by priority (highest first), then creation time.
+func (s *Store) List() ([]Rule, error) {
+	rows, err := s.db.Query("SELECT data FROM rules ORDER BY priority DESC, created_at ASC, id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	rules := []Rule{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var r Rule
+		if err := json.Unmarshal([]byte(data), &r); err != nil {
+			return nil, err
+		}
+		rules = append(rules, r)
+	}
+	return rules, rows.Err()
+}
+
+func (s *Store) Get(id string) (*Rule, error) {
+	var data string
+	err := s.db.QueryRow("SELECT data FROM rules WHERE id = ?", id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var r Rule
+	if err := json.Unmarshal([]byte(data), &r); err != nil {
+		return nil, err
+	}
+	return &r, nil
+}
+
+// Create assigns an ID and timestamps to the rule and stores it.
+func (s *Store) Create(r *Rule) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	r.ID = uuid.New().String()
+	r.CreatedAt = now
+	r.UpdatedAt = now
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT INTO rules (id, priority, data, created_at) VALUES (?, ?, ?, ?)",
+		r.ID, r.Priority, string(data), now)
+	return err
+}
+
+// Update replaces an existing rule, keeping its ID and creation time.
+func (s *Store) Update(r *Rule) error {
+	existing, err := s.Get(r.ID)
+	if err != nil {
+		return err
+	}
+	r.CreatedAt = existing.CreatedAt
+	r.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("UPDATE rules SET priority = ?, data = ? WHERE id = ?", r.Priority, string(data), r.ID)
+	return err
+}
+
+func (s *Store) Delete(id string) error {
+	res, err := s.db.Exec("DELETE FROM rules WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (s *Store) ListTemplates() ([]Template, error) {
+	rows, err := s.db.Query("SELECT name, body, updated_at FROM templates ORDER BY name")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	templates := []Template{}
+	for rows.Next() {
+		var t Template
+		if err := rows.Scan(&t.Name, &t.Body, &t.UpdatedAt); err != nil {
+			return nil, err
+		}
+		templates = append(templates, t)
+	}
+	return templates, rows.Err()
+}
+
+func (s *Store) GetTemplate(name string) (*Template, error) {
+	var t Template
+	err := s.db.QueryRow("SELECT name, body, updated_at FROM templates WHERE name = ?", name).
+		Scan(&t.Name, &t.Body, &t.UpdatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// SaveTemplate creates or replaces a template by name.
+func (s *Store) SaveTemplate(t *Template) error {
+	t.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	_, err := s.db.Exec(`
+		INSERT INTO templates (name, body, updated_at) VALUES (?, ?, ?)
+		ON CONFLICT(name) DO UPDATE SET body = excluded.body, updated_at = excluded.updated_at
+	`, t.Name, t.Body, t.UpdatedAt)
+	return err
+}
+
+func (s *Store) DeleteTemplate(name string) error {
+	res, err := s.db.Exec("DELETE FROM templates WHERE name = ?", name)
+	if err != nil {
+		return err
+	}
+	return requireAffected(res)
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
+
+func requireAffected(res sql.Result) error {
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
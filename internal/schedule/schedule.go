@@ -0,0 +1,162 @@
+package schedule
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+const dateLayout = "2006-01-02"
+
+// maxLookback bounds how far ClosedSince searches for the last opening period.
+const maxLookback = 366
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+// Period is an opening period within a single day, e.g. 09:00-13:00.
+type Period struct {
+	Start string `json:"start"`
+	End   string `json:"end"`
+}
+
+// BusinessHours is a weekly opening schedule with holiday exceptions.
+// Weekly is keyed by day ("sun"-"sat"); days without periods are closed.
+type BusinessHours struct {
+	Enabled     bool                `json:"enabled"`
+	Timezone    string              `json:"timezone"`
+	Weekly      map[string][]Period `json:"weekly"`
+	Holidays    []string            `json:"holidays"`
+	AwayMessage string              `json:"awayMessage"`
+}
+
+// ParseClock converts "HH:MM" into minutes since midnight.
+func ParseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", s)
+	if err != nil {
+		return 0, fmt.Errorf("time %q must be in HH:MM format", s)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+// ParseWeekday converts a three-letter day name ("mon") into a time.Weekday.
+func ParseWeekday(s string) (time.Weekday, error) {
+	day, ok := weekdays[strings.ToLower(s)]
+	if !ok {
+		return 0, fmt.Errorf("unknown day %q", s)
+	}
+	return day, nil
+}
+
+func (p Period) Validate() error {
+	start, err := ParseClock(p.Start)
+	if err != nil {
+		return err
+	}
+	end, err := ParseClock(p.End)
+	if err != nil {
+		return err
+	}
+	if start >= end {
+		return fmt.Errorf("period %s-%s must start before it ends", p.Start, p.End)
+	}
+	return nil
+}
+
+func (b *BusinessHours) Validate() error {
+	if _, err := time.LoadLocation(b.Timezone); err != nil {
+		return fmt.Errorf("unknown timezone %q", b.Timezone)
+	}
+	for day, periods := range b.Weekly {
+		if _, err := ParseWeekday(day); err != nil {
+			return err
+		}
+		for _, p := range periods {
+			if err := p.Validate(); err != nil {
+				return err
+			}
+		}
+	}
+	for _, h := range b.Holidays {
+		if _, err := time.Parse(dateLayout, h); err != nil {
+			return fmt.Errorf("holiday %q must be in YYYY-MM-DD format", h)
+		}
+	}
+	return nil
+}
+
+// IsOpen reports whether t falls inside opening hours. A disabled schedule is always open.
+func (b *BusinessHours) IsOpen(t time.Time) bool {
+	if !b.Enabled {
+		return true
+	}
+
+	local := t.In(b.location())
+	minute := local.Hour()*60 + local.Minute()
+	for _, p := range b.periods(local) {
+		start, _ := ParseClock(p.Start)
+		end, _ := ParseClock(p.End)
+		if minute >= start && minute < end {
+			return true
+		}
+	}
+	return false
+}
+
+// ClosedSince returns when the current closed window began, i.e. the end of the
+// most recent opening period at or before t. It returns the zero time if there
+// was no opening period within the lookback range.
+func (b *BusinessHours) ClosedSince(t time.Time) time.Time {
+	loc := b.location()
+	local := t.In(loc)
+	midnight := time.Date(local.Year(), local.Month(), local.Day(), 0, 0, 0, 0, loc)
+
+	for d := 0; d <= maxLookback; d++ {
+		day := midnight.AddDate(0, 0, -d)
+
+		var latest time.Time
+		for _, p := range b.periods(day) {
+			end, _ := ParseClock(p.End)
+			closeAt := day.Add(time.Duration(end) * time.Minute)
+			if !closeAt.After(t) && closeAt.After(latest) {
+				latest = closeAt
+			}
+		}
+		if !latest.IsZero() {
+			return latest
+		}
+	}
+	return time.Time{}
+}
+
+// periods returns the opening periods for the calendar day of local, or none on holidays.
+func (b *BusinessHours) periods(local time.Time) []Period {
+	date := local.Format(dateLayout)
+	for _, h := range b.Holidays {
+		if h == date {
+			return nil
+		}
+	}
+
+	for day, periods := range b.Weekly {
+		if wd, err := ParseWeekday(day); err == nil && wd == local.Weekday() {
+			return periods
+		}
+	}
+	return nil
+}
+
+func (b *BusinessHours) location() *time.Location {
+	loc, err := time.LoadLocation(b.Timezone)
+	if err != nil {
+		return time.UTC
+	}
+	return loc
+}
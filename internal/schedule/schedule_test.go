@@ -0,0 +1,102 @@
+package schedule
+
+import (
+	"testing"
+	"time"
+)
+
+func officeHours() BusinessHours {
+	weekday := []Period{{Start: "09:00", End: "13:00"}, {Start: "14:00", End: "18:00"}}
+	return BusinessHours{
+		Enabled:  true,
+		Timezone: "Asia/Kuala_Lumpur",
+		Weekly: map[string][]Period{
+			"mon": weekday,
+			"tue": weekday,
+			"wed": weekday,
+			"thu": weekday,
+			"fri": weekday,
+		},
+		Holidays: []string{"2026-02-17"},
+	}
+}
+
+func TestBusinessHours_IsOpen(t *testing.T) {
+	b := officeHours()
+	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
+
+	// 2026-02-16 is a Monday, 2026-02-17 a holiday (Tuesday)
+	tests := []struct {
+		name string
+		at   time.Time
+		open bool
+	}{
+		{"monday morning", time.Date(2026, 2, 16, 10, 0, 0, 0, loc), true},
+		{"monday lunch", time.Date(2026, 2, 16, 13, 30, 0, 0, loc), false},
+		{"monday afternoon", time.Date(2026, 2, 16, 17, 59, 0, 0, loc), true},
+		{"monday evening", time.Date(2026, 2, 16, 18, 0, 0, 0, loc), false},
+		{"holiday", time.Date(2026, 2, 17, 10, 0, 0, 0, loc), false},
+		{"saturday", time.Date(2026, 2, 21, 10, 0, 0, 0, loc), false},
+	}
+	for _, tt := range tests {
+		if got := b.IsOpen(tt.at); got != tt.open {
+			t.Errorf("%s: expected open=%v, got %v", tt.name, tt.open, got)
+		}
+	}
+}
+
+func TestBusinessHours_DisabledAlwaysOpen(t *testing.T) {
+	b := officeHours()
+	b.Enabled = false
+	if !b.IsOpen(time.Date(2026, 2, 21, 3, 0, 0, 0, time.UTC)) {
+		t.Error("disabled schedule should always be open")
+	}
+}
+
+func TestBusinessHours_ClosedSince(t *testing.T) {
+	b := officeHours()
+	loc, _ := time.LoadLocation("Asia/Kuala_Lumpur")
+
+	tests := []struct {
+		name string
+		at   time.Time
+		want time.Time
+	}{
+		{"lunch break", time.Date(2026, 2, 16, 13, 30, 0, 0, loc), time.Date(2026, 2, 16, 13, 0, 0, 0, loc)},
+		{"monday night", time.Date(2026, 2, 16, 23, 0, 0, 0, loc), time.Date(2026, 2, 16, 18, 0, 0, 0, loc)},
+		{"across holiday", time.Date(2026, 2, 18, 8, 0, 0, 0, loc), time.Date(2026, 2, 16, 18, 0, 0, 0, loc)},
+		{"weekend", time.Date(2026, 2, 22, 12, 0, 0, 0, loc), time.Date(2026, 2, 20, 18, 0, 0, 0, loc)},
+	}
+	for _, tt := range tests {
+		if got := b.ClosedSince(tt.at); !got.Equal(tt.want) {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestBusinessHours_ClosedSinceNeverOpen(t *testing.T) {
+	b := BusinessHours{Enabled: true}
+	if got := b.ClosedSince(time.Now()); !got.IsZero() {
+		t.Errorf("expected zero time for schedule with no periods, got %v", got)
+	}
+}
+
+func TestBusinessHours_Validate(t *testing.T) {
+	valid := officeHours()
+	if err := valid.Validate(); err != nil {
+		t.Fatalf("expected valid schedule, got %v", err)
+	}
+
+	invalid := []BusinessHours{
+		{Timezone: "Nowhere/City"},
+		{Weekly: map[string][]Period{"funday": {{Start: "09:00", End: "17:00"}}}},
+		{Weekly: map[string][]Period{"mon": {{Start: "17:00", End: "09:00"}}}},
+		{Weekly: map[string][]Period{"mon": {{Start: "9", End: "17:00"}}}},
+		{Holidays: []string{"25/12/2026"}},
+	}
+	for i, b := range invalid {
+		if err := b.Validate(); err == nil {
+			t.Errorf("schedule %d: expected validation error", i)
+		}
+	}
+}
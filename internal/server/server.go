@@ -1,6 +1,7 @@
 package server
 
 import (
+	"errors"
 	"time"
 
 	"github.com/gofiber/fiber/v2"
@@ -9,8 +10,11 @@ import (
 	"github.com/rs/zerolog"
 
 	"github.com/AsyrafHussin/wa-gateway-go/config"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/audit"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/cache"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/handler"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/jwtauth"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/middleware"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/webhook"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
@@ -26,7 +30,7 @@ type Server struct {
 	Logger  zerolog.Logger
 }
 
-func New(cfg *config.Config, manager *whatsapp.DeviceManager, hub *ws.Hub, dispatcher *webhook.Dispatcher, phoneCache *cache.PhoneCache, logger zerolog.Logger) *Server {
+func New(cfg *config.Config, manager *whatsapp.DeviceManager, hub *ws.Hub, dispatcher *webhook.Dispatcher, keys *apikey.Store, verifier *jwtauth.Verifier, auditLog *audit.Store, phoneCache *cache.PhoneCache, logger zerolog.Logger) *Server {
 	app := fiber.New(fiber.Config{
 		DisableStartupMessage: true,
 		ErrorHandler: func(c *fiber.Ctx, err error) error {
@@ -52,35 +56,110 @@ func New(cfg *config.Config, manager *whatsapp.DeviceManager, hub *ws.Hub, dispa
 	app.Use(middleware.RequestLogger(logger))
 
 	v := validator.New(cfg.PhoneCountryCode, cfg.PhoneMinLength, cfg.PhoneMaxLength)
-	auth := middleware.NewAuth(cfg.APIKey)
+	auth := middleware.NewAuth(cfg.APIKey, keys, verifier)
+
+	// Named API keys are limited to routes for their scopes
+	devicesRead := middleware.Scope(apikey.ScopeDevicesRead)
+	devicesWrite := middleware.Scope(apikey.ScopeDevicesWrite)
+	messagesSend := middleware.Scope(apikey.ScopeMessagesSend)
+	contactsRead := middleware.Scope(apikey.ScopeContactsRead)
+	admin := middleware.Scope(apikey.ScopeAdmin)
+
+	// Sends and changes are audited, including attempts rejected by scope
+	audited := middleware.NewAudit(auditLog, logger).Record
 
 	// Health (no auth)
 	healthHandler := handler.NewHealth(manager, logger)
 	app.Get("/health", healthHandler.Basic)
-	app.Get("/health/detailed", auth.Require(), healthHandler.Detailed)
+	app.Get("/health/detailed", auth.Require(), devicesRead, healthHandler.Detailed)
+
+	// WebSocket commands share validation with the REST handlers
+	hub.SetCommandHandler(handler.NewCommands(manager, v, auditLog, logger))
 
 	// WebSocket (origin check + first-message auth)
-	wsHandler := handler.NewWS(hub, cfg.APIKey, time.Duration(cfg.WSAuthTimeout)*time.Second, cfg.WSAllowedOrigins, logger)
+	wsAuth := func(key string) (ws.Principal, error) {
+		p, err := auth.Authenticate(key)
+		if errors.Is(err, apikey.ErrNotFound) || errors.Is(err, jwtauth.ErrInvalidToken) {
+			return nil, ws.ErrInvalidKey
+		}
+		if err != nil {
+			return nil, err
+		}
+		return p, nil
+	}
+	wsHandler := handler.NewWS(hub, wsAuth, time.Duration(cfg.WSAuthTimeout)*time.Second, cfg.WSAllowedOrigins, logger)
 	app.Get("/ws", wsHandler.Upgrade)
 
+	// Server-Sent Events (header or apiKey query auth)
+	eventsHandler := handler.NewEvents(hub, logger)
+	app.Get("/events", auth.RequireAllowQuery(), middleware.RateLimit(cfg.RateLimitDevices), devicesRead, eventsHandler.Stream)
+
 	// Authenticated routes
 	api := app.Group("", auth.Require())
 
-	deviceHandler := handler.NewDevice(manager, logger)
-	api.Post("/devices", middleware.RateLimit(cfg.RateLimitDevices), deviceHandler.Connect)
-	api.Delete("/devices/:token", middleware.RateLimit(cfg.RateLimitDevices), deviceHandler.Disconnect)
+	deviceHandler := handler.NewDevice(manager, keys, logger)
+	api.Get("/devices", middleware.RateLimit(cfg.RateLimitDevices), devicesRead, deviceHandler.List)
+	api.Post("/devices", middleware.RateLimit(cfg.RateLimitDevices), audited("device.connect"), devicesWrite, deviceHandler.Connect)
+	api.Get("/devices/:token", middleware.RateLimit(cfg.RateLimitDevices), devicesRead, deviceHandler.Get)
+	api.Get("/devices/:token/qr", middleware.RateLimit(cfg.RateLimitDevices), audited("device.qr"), devicesWrite, deviceHandler.QR)
+	api.Delete("/devices/:token", middleware.RateLimit(cfg.RateLimitDevices), audited("device.disconnect"), devicesWrite, deviceHandler.Disconnect)
+	api.Put("/devices/:token/tenant", middleware.RateLimit(cfg.RateLimitDevices), audited("device.tenant"), admin, deviceHandler.SetTenant)
+
+	settingsHandler := handler.NewSettings(manager, logger)
+	api.Get("/devices/:token/settings", middleware.RateLimit(cfg.RateLimitDevices), devicesRead, settingsHandler.Get)
+	api.Patch("/devices/:token/settings", middleware.RateLimit(cfg.RateLimitDevices), audited("settings.update"), devicesWrite, settingsHandler.Update)
 
 	messageHandler := handler.NewMessage(manager, v, logger)
-	api.Post("/messages", middleware.RateLimit(cfg.RateLimitMessages), messageHandler.Send)
+	api.Post("/messages", middleware.RateLimit(cfg.RateLimitMessages), audited("message.send"), messagesSend, messageHandler.Send)
+
+	presenceHandler := handler.NewPresence(manager, v, logger)
+	api.Post("/presence", middleware.RateLimit(cfg.RateLimitMessages), audited("presence.set"), messagesSend, presenceHandler.Set)
+	api.Post("/presence/chat", middleware.RateLimit(cfg.RateLimitMessages), audited("presence.chat"), messagesSend, presenceHandler.Chat)
+	api.Post("/presence/subscribe", middleware.RateLimit(cfg.RateLimitMessages), audited("presence.subscribe"), messagesSend, presenceHandler.Subscribe)
 
 	validationHandler := handler.NewValidation(manager, v, phoneCache, logger)
-	api.Post("/validate/phone", middleware.RateLimit(cfg.RateLimitValidate), validationHandler.ValidatePhone)
+	api.Post("/validate/phone", middleware.RateLimit(cfg.RateLimitValidate), contactsRead, validationHandler.ValidatePhone)
 
 	contactHandler := handler.NewContact(manager, logger)
-	api.Get("/contacts/:token", middleware.RateLimit(cfg.RateLimitMessages), contactHandler.List)
+	api.Get("/contacts/:token", middleware.RateLimit(cfg.RateLimitMessages), contactsRead, contactHandler.List)
+
+	rulesHandler := handler.NewRules(manager, logger)
+	api.Get("/rules/:token", middleware.RateLimit(cfg.RateLimitMessages), devicesRead, rulesHandler.List)
+	api.Post("/rules/:token", middleware.RateLimit(cfg.RateLimitDevices), audited("rule.create"), devicesWrite, rulesHandler.Create)
+	api.Get("/rules/:token/:id", middleware.RateLimit(cfg.RateLimitMessages), devicesRead, rulesHandler.Get)
+	api.Put("/rules/:token/:id", middleware.RateLimit(cfg.RateLimitDevices), audited("rule.update"), devicesWrite, rulesHandler.Update)
+	api.Delete("/rules/:token/:id", middleware.RateLimit(cfg.RateLimitDevices), audited("rule.delete"), devicesWrite, rulesHandler.Delete)
+	api.Get("/templates/:token", middleware.RateLimit(cfg.RateLimitMessages), devicesRead, rulesHandler.ListTemplates)
+	api.Put("/templates/:token/:name", middleware.RateLimit(cfg.RateLimitDevices), audited("template.save"), devicesWrite, rulesHandler.SaveTemplate)
+	api.Delete("/templates/:token/:name", middleware.RateLimit(cfg.RateLimitDevices), audited("template.delete"), devicesWrite, rulesHandler.DeleteTemplate)
+
+	optOutHandler := handler.NewOptOut(manager, v, dispatcher, logger)
+	api.Get("/optouts/:token", middleware.RateLimit(cfg.RateLimitMessages), contactsRead, optOutHandler.List)
+	api.Post("/optouts/:token", middleware.RateLimit(cfg.RateLimitDevices), audited("optout.add"), devicesWrite, optOutHandler.Add)
+	api.Delete("/optouts/:token/:phone", middleware.RateLimit(cfg.RateLimitDevices), audited("optout.remove"), devicesWrite, optOutHandler.Remove)
+
+	webhookHandler := handler.NewWebhook(dispatcher, logger)
+	api.Get("/webhooks/dead-letters", middleware.RateLimit(cfg.RateLimitDevices), admin, webhookHandler.DeadLetters)
+	api.Post("/webhooks/dead-letters/:id/replay", middleware.RateLimit(cfg.RateLimitDevices), audited("webhook.replay"), admin, webhookHandler.Replay)
+	api.Get("/webhooks/deliveries", middleware.RateLimit(cfg.RateLimitDevices), admin, webhookHandler.Deliveries)
+	api.Post("/webhooks/deliveries/:id/redeliver", middleware.RateLimit(cfg.RateLimitDevices), audited("webhook.redeliver"), admin, webhookHandler.Redeliver)
+	api.Get("/webhooks", middleware.RateLimit(cfg.RateLimitDevices), admin, webhookHandler.List)
+	api.Post("/webhooks", middleware.RateLimit(cfg.RateLimitDevices), audited("webhook.create"), admin, webhookHandler.Create)
+	api.Get("/webhooks/:id", middleware.RateLimit(cfg.RateLimitDevices), admin, webhookHandler.Get)
+	api.Put("/webhooks/:id", middleware.RateLimit(cfg.RateLimitDevices), audited("webhook.update"), admin, webhookHandler.Update)
+	api.Delete("/webhooks/:id", middleware.RateLimit(cfg.RateLimitDevices), audited("webhook.delete"), admin, webhookHandler.Delete)
+
+	keysHandler := handler.NewKeys(keys, logger)
+	api.Get("/keys", middleware.RateLimit(cfg.RateLimitDevices), admin, keysHandler.List)
+	api.Post("/keys", middleware.RateLimit(cfg.RateLimitDevices), audited("key.create"), admin, keysHandler.Create)
+	api.Get("/keys/:id", middleware.RateLimit(cfg.RateLimitDevices), admin, keysHandler.Get)
+	api.Delete("/keys/:id", middleware.RateLimit(cfg.RateLimitDevices), audited("key.revoke"), admin, keysHandler.Delete)
+
+	auditHandler := handler.NewAudit(auditLog, logger)
+	api.Get("/audit", middleware.RateLimit(cfg.RateLimitDevices), admin, auditHandler.List)
 
 	cacheHandler := handler.NewCache(phoneCache, logger)
-	api.Delete("/cache", middleware.RateLimit(cfg.RateLimitDevices), cacheHandler.Clear)
+	api.Delete("/cache", middleware.RateLimit(cfg.RateLimitDevices), audited("cache.clear"), admin, cacheHandler.Clear)
 
 	return &Server{
 		App:     app,
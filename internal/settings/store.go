@@ -0,0 +1,121 @@
+package settings
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/schedule"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
+
+	_ "modernc.org/sqlite"
+)
+
+// CallPolicy controls how incoming voice/video calls are handled.
+type CallPolicy struct {
+	AutoReject    bool   `json:"autoReject"`
+	RejectMessage string `json:"rejectMessage"`
+}
+
+// Webhook routes the device's webhook events to its own endpoint instead of
+// the global WEBHOOK_URL. An empty URL uses the global endpoint and drops the
+// secret when saved.
+type Webhook struct {
+	URL       string `json:"url"`
+	Secret    string `json:"secret,omitempty"`
+	HasSecret bool   `json:"hasSecret"`
+}
+
+func (w *Webhook) Validate() error {
+	if w.URL == "" {
+		return nil
+	}
+	return validator.ValidateURL(w.URL)
+}
+
+// Settings holds the per-device configuration persisted next to the session.
+type Settings struct {
+	Calls         CallPolicy             `json:"calls"`
+	BusinessHours schedule.BusinessHours `json:"businessHours"`
+	Webhook       Webhook                `json:"webhook"`
+}
+
+func (st *Settings) Validate() error {
+	if err := st.BusinessHours.Validate(); err != nil {
+		return fmt.Errorf("businessHours: %w", err)
+	}
+	if err := st.Webhook.Validate(); err != nil {
+		return fmt.Errorf("webhook: %w", err)
+	}
+	return nil
+}
+
+// Redacted returns a copy without the webhook secret, for API responses.
+func (st Settings) Redacted() Settings {
+	st.Webhook.Secret = ""
+	return st
+}
+
+type Store struct {
+	db *sql.DB
+}
+
+func NewStore(dbPath string) (*Store, error) {
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS settings (
+			id         INTEGER PRIMARY KEY CHECK (id = 1),
+			data       TEXT NOT NULL,
+			updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Store{db: db}, nil
+}
+
+// Get returns the stored settings, or zero-value defaults if none were saved.
+func (s *Store) Get() (*Settings, error) {
+	var data string
+	err := s.db.QueryRow("SELECT data FROM settings WHERE id = 1").Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return &Settings{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var st Settings
+	if err := json.Unmarshal([]byte(data), &st); err != nil {
+		return nil, err
+	}
+	return &st, nil
+}
+
+func (s *Store) Save(st *Settings) error {
+	if st.Webhook.URL == "" {
+		st.Webhook.Secret = ""
+	}
+	st.Webhook.HasSecret = st.Webhook.Secret != ""
+	data, err := json.Marshal(st)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec(`
+		INSERT INTO settings (id, data, updated_at) VALUES (1, ?, CURRENT_TIMESTAMP)
+		ON CONFLICT(id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at
+	`, string(data))
+	return err
+}
+
+func (s *Store) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,100 @@
+package settings
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func newTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := NewStore(filepath.Join(t.TempDir(), "settings.db"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	t.Cleanup(func() { _ = s.Close() })
+	return s
+}
+
+func TestStore_GetDefaults(t *testing.T) {
+	s := newTestStore(t)
+
+	st, err := s.Get()
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if st.Calls.AutoReject {
+		t.Error("expected AutoReject false by default")
+	}
+	if st.Calls.RejectMessage != "" {
+		t.Errorf("expected empty RejectMessage, got %q", st.Calls.RejectMessage)
+	}
+}
+
+func TestStore_SaveAndGet(t *testing.T) {
+	s := newTestStore(t)
+
+	in := &Settings{Calls: CallPolicy{AutoReject: true, RejectMessage: "Calls are not supported"}}
+	if err := s.Save(in); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	out, err := s.Get()
+	if err != nil {
+		t.Fatalf("get failed: %v", err)
+	}
+	if !out.Calls.AutoReject {
+		t.Error("expected AutoReject true")
+	}
+	if out.Calls.RejectMessage != "Calls are not supported" {
+		t.Errorf("unexpected RejectMessage %q", out.Calls.RejectMessage)
+	}
+}
+
+func TestStore_SaveOverwrites(t *testing.T) {
+	s := newTestStore(t)
+
+	_ = s.Save(&Settings{Calls: CallPolicy{AutoReject: true}})
+	if err := s.Save(&Settings{Calls: CallPolicy{AutoReject: false}}); err != nil {
+		t.Fatalf("second save failed: %v", err)
+	}
+
+	out, _ := s.Get()
+	if out.Calls.AutoReject {
+		t.Error("expected second save to overwrite the first")
+	}
+}
+
+func TestStore_Webhook(t *testing.T) {
+	s := newTestStore(t)
+
+	in := &Settings{Webhook: Webhook{URL: "https://client.example.com/hook", Secret: "s3cret"}}
+	if err := in.Validate(); err != nil {
+		t.Fatalf("unexpected validation error: %v", err)
+	}
+	if err := s.Save(in); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+
+	out, _ := s.Get()
+	if out.Webhook.URL != "https://client.example.com/hook" || out.Webhook.Secret != "s3cret" || !out.Webhook.HasSecret {
+		t.Fatalf("unexpected webhook: %+v", out.Webhook)
+	}
+	if r := out.Redacted(); r.Webhook.Secret != "" || !r.Webhook.HasSecret {
+		t.Fatalf("expected redacted secret, got %+v", r.Webhook)
+	}
+
+	// Clearing the URL drops the secret too
+	out.Webhook.URL = ""
+	if err := s.Save(out); err != nil {
+		t.Fatalf("save failed: %v", err)
+	}
+	out, _ = s.Get()
+	if out.Webhook.Secret != "" || out.Webhook.HasSecret {
+		t.Fatalf("expected secret to be cleared, got %+v", out.Webhook)
+	}
+
+	bad := &Settings{Webhook: Webhook{URL: "not-a-url"}}
+	if err := bad.Validate(); err == nil {
+		t.Fatal("expected invalid webhook URL to fail validation")
+	}
+}
@@ -0,0 +1,149 @@
+package webhook
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// FormBatches groups unbatched due deliveries per endpoint into batches of up
+// to size. An endpoint's deliveries are batched once size of them are waiting
+// or the oldest has waited for window. With exclusive set, an endpoint with a
+// batch still pending gets no new batch, so batches are delivered in order.
+func (o *Outbox) FormBatches(now time.Time, size int, window time.Duration, exclusive bool) error {
+	rows, err := o.db.Query(`
+		SELECT subscription_id, url, COUNT(*), MIN(next_attempt_at) FROM outbox
+		WHERE batch_id = '' AND next_attempt_at <= ?
+		GROUP BY subscription_id, url
+	`, now.UnixMilli())
+	if err != nil {
+		return err
+	}
+
+	type group struct {
+		subscriptionID, url string
+		count               int
+		oldest              int64
+	}
+	var groups []group
+	for rows.Next() {
+		var g group
+		if err := rows.Scan(&g.subscriptionID, &g.url, &g.count, &g.oldest); err != nil {
+			_ = rows.Close()
+			return err
+		}
+		groups = append(groups, g)
+	}
+	_ = rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	expired := now.Add(-window).UnixMilli()
+	for _, g := range groups {
+		if exclusive {
+			var pending bool
+			err := o.db.QueryRow(
+				"SELECT EXISTS(SELECT 1 FROM outbox WHERE batch_id != '' AND subscription_id = ? AND url = ?)",
+				g.subscriptionID, g.url,
+			).Scan(&pending)
+			if err != nil {
+				return err
+			}
+			if pending {
+				continue
+			}
+		}
+
+		for g.count >= size || (g.count > 0 && g.oldest <= expired) {
+			_, err := o.db.Exec(`
+				UPDATE outbox SET batch_id = ? WHERE id IN (
+					SELECT id FROM outbox
+					WHERE batch_id = '' AND subscription_id = ? AND url = ? AND next_attempt_at <= ?
+					ORDER BY id LIMIT ?
+				)
+			`, uuid.New().String(), g.subscriptionID, g.url, now.UnixMilli(), size)
+			if err != nil {
+				return err
+			}
+			g.count -= size
+			if exclusive {
+				break
+			}
+		}
+	}
+	return nil
+}
+
+// ClearBatches returns every delivery to unbatched, so batches formed before
+// batching was turned off are sent one event per request.
+func (o *Outbox) ClearBatches() error {
+	_, err := o.db.Exec("UPDATE outbox SET batch_id = '' WHERE batch_id != ''")
+	return err
+}
+
+// DueBatches returns up to limit batches whose next attempt is at or before
+// now, oldest first, each ordered by enqueue time.
+func (o *Outbox) DueBatches(now time.Time, limit int) ([][]Delivery, error) {
+	rows, err := o.db.Query(`
+		SELECT `+deliveryColumns+` FROM outbox
+		WHERE batch_id IN (
+			SELECT batch_id FROM outbox WHERE batch_id != '' AND next_attempt_at <= ?
+			GROUP BY batch_id ORDER BY MIN(next_attempt_at), MIN(id) LIMIT ?
+		)
+		ORDER BY next_attempt_at, batch_id, id
+	`, now.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	deliveries, err := scanDeliveries(rows)
+	if err != nil {
+		return nil, err
+	}
+
+	var batches [][]Delivery
+	index := map[string]int{}
+	for _, d := range deliveries {
+		i, ok := index[d.BatchID]
+		if !ok {
+			i = len(batches)
+			index[d.BatchID] = i
+			batches = append(batches, nil)
+		}
+		batches[i] = append(batches[i], d)
+	}
+	return batches, nil
+}
+
+// encodeBatch renders deliveries as one JSON array body: the stored payloads
+// for FormatJSON, or a CloudEvents batch for either CloudEvents format, since
+// binary mode cannot carry more than one event.
+func encodeBatch(format, source string, deliveries []Delivery) ([]byte, http.Header, error) {
+	header := http.Header{}
+	if format != FormatCloudEvents && format != FormatCloudEventsBinary {
+		payloads := make([][]byte, len(deliveries))
+		for i, d := range deliveries {
+			payloads[i] = d.Payload
+		}
+		header.Set("Content-Type", "application/json")
+		return append(append([]byte("["), bytes.Join(payloads, []byte(","))...), ']'), header, nil
+	}
+
+	events := make([]CloudEvent, len(deliveries))
+	for i, d := range deliveries {
+		ce, err := cloudEvent(source, d)
+		if err != nil {
+			return nil, nil, err
+		}
+		events[i] = ce
+	}
+	body, err := json.Marshal(events)
+	if err != nil {
+		return nil, nil, err
+	}
+	header.Set("Content-Type", "application/cloudevents-batch+json")
+	return body, header, nil
+}
@@ -0,0 +1,228 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/webhooksig"
+)
+
+func TestOutbox_FormBatches(t *testing.T) {
+	o := newTestOutbox(t)
+
+	enqueue := func(subscriptionID string, n int) {
+		t.Helper()
+		for i := 0; i < n; i++ {
+			if _, err := o.Enqueue("message.receipt", "60123456789", subscriptionID, "http://example.com", "", []byte(`{}`)); err != nil {
+				t.Fatalf("enqueue failed: %v", err)
+			}
+		}
+	}
+	sizes := func() []int {
+		t.Helper()
+		batches, err := o.DueBatches(time.Now(), 10)
+		if err != nil {
+			t.Fatalf("due batches failed: %v", err)
+		}
+		var got []int
+		for _, b := range batches {
+			got = append(got, len(b))
+		}
+		return got
+	}
+
+	enqueue("full", 5)
+	enqueue("partial", 2)
+	if err := o.FormBatches(time.Now(), 2, time.Hour, false); err != nil {
+		t.Fatalf("form failed: %v", err)
+	}
+	// "full" fills two batches and keeps one waiting; "partial" fills one
+	if got := sizes(); len(got) != 3 || got[0] != 2 || got[1] != 2 || got[2] != 2 {
+		t.Fatalf("expected three batches of 2, got %v", got)
+	}
+
+	// Once the window passes, the leftover delivery is sent on its own
+	if err := o.FormBatches(time.Now().Add(2*time.Hour), 2, time.Hour, false); err != nil {
+		t.Fatalf("form failed: %v", err)
+	}
+	if got := sizes(); len(got) != 4 {
+		t.Fatalf("expected the leftover to form a fourth batch, got %v", got)
+	}
+}
+
+func TestOutbox_FormBatchesExclusive(t *testing.T) {
+	o := newTestOutbox(t)
+	for i := 0; i < 4; i++ {
+		if _, err := o.Enqueue("message.receipt", "60123456789", "", "http://example.com", "", []byte(`{}`)); err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		if err := o.FormBatches(time.Now(), 2, time.Hour, true); err != nil {
+			t.Fatalf("form failed: %v", err)
+		}
+	}
+	batches, _ := o.DueBatches(time.Now(), 10)
+	if len(batches) != 1 || len(batches[0]) != 2 {
+		t.Fatalf("expected a single pending batch per endpoint, got %d", len(batches))
+	}
+}
+
+func TestDispatcher_Batching(t *testing.T) {
+	type hit struct {
+		header http.Header
+		body   []byte
+	}
+	var calls atomic.Int32
+	received := make(chan hit, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- hit{header: r.Header, body: body}
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	d, err := NewDispatcher(Config{
+		URL:         srv.URL,
+		Secret:      "secret",
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		RetryBase:   10 * time.Millisecond,
+		RetryMax:    10 * time.Millisecond,
+		BatchSize:   3,
+		BatchWindow: time.Hour,
+		DBPath:      filepath.Join(t.TempDir(), "webhooks.db"),
+	}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+	d.Start()
+
+	events := []string{"contacts.new", "message.receipt", "message.autoreply"}
+	for _, e := range events {
+		d.Send(e, "60123456789", nil)
+	}
+
+	var hits []hit
+	for len(hits) < 2 {
+		select {
+		case h := <-received:
+			hits = append(hits, h)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d requests", len(hits))
+		}
+	}
+
+	// The failed batch is retried with the same ID and contents
+	if string(hits[0].body) != string(hits[1].body) || hits[0].header.Get(webhooksig.HeaderID) != hits[1].header.Get(webhooksig.HeaderID) {
+		t.Fatalf("expected retry to resend the same batch")
+	}
+
+	var payloads []Payload
+	if err := json.Unmarshal(hits[1].body, &payloads); err != nil {
+		t.Fatalf("expected JSON array body, got %s: %v", hits[1].body, err)
+	}
+	if len(payloads) != len(events) {
+		t.Fatalf("expected %d events in batch, got %d", len(events), len(payloads))
+	}
+	for i, p := range payloads {
+		if p.Event != events[i] {
+			t.Errorf("batch[%d] = %s, want %s", i, p.Event, events[i])
+		}
+	}
+	if err := webhooksig.Verify(hits[1].header, hits[1].body, []string{"secret"}, 0); err != nil {
+		t.Errorf("expected one signature over the batch: %v", err)
+	}
+
+	waitFor(t, func() bool {
+		n, _ := d.outbox.Pending()
+		return n == 0
+	})
+	select {
+	case h := <-received:
+		t.Fatalf("unexpected extra request: %s", h.body)
+	case <-time.After(100 * time.Millisecond):
+	}
+}
+
+func TestDispatcher_BatchingOffSendsStaleBatchesSingly(t *testing.T) {
+	received := make(chan []byte, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- body
+	}))
+	defer srv.Close()
+
+	// A batch formed while batching was on is left in the outbox
+	dbPath := filepath.Join(t.TempDir(), "webhooks.db")
+	cfg := Config{URL: srv.URL, Timeout: time.Second, BatchSize: 2, BatchWindow: time.Hour, DBPath: dbPath}
+	d, err := NewDispatcher(cfg, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	d.Send("contacts.new", "60123456789", nil)
+	d.Send("message.receipt", "60123456789", nil)
+	if err := d.outbox.FormBatches(time.Now(), 2, time.Hour, false); err != nil {
+		t.Fatalf("form failed: %v", err)
+	}
+	_ = d.Close()
+
+	cfg.BatchSize = 1
+	d, err = NewDispatcher(cfg, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to reopen dispatcher: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+	d.Start()
+
+	for i := 0; i < 2; i++ {
+		select {
+		case body := <-received:
+			var p Payload
+			if err := json.Unmarshal(body, &p); err != nil {
+				t.Fatalf("expected a single JSON object, got %s: %v", body, err)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d requests", i)
+		}
+	}
+}
+
+func TestEncodeBatch(t *testing.T) {
+	deliveries := []Delivery{
+		{WebhookID: "a", Payload: []byte(`{"event":"contacts.new","token":"60123456789","data":null,"timestamp":"2026-02-17T10:30:00Z"}`)},
+		{WebhookID: "b", Payload: []byte(`{"event":"message.receipt","token":"60123456789","data":{"type":"read"},"timestamp":"2026-02-17T10:30:01Z"}`)},
+	}
+
+	body, header, err := encodeBatch(FormatJSON, DefaultSource, deliveries)
+	if err != nil || header.Get("Content-Type") != "application/json" {
+		t.Fatalf("json: unexpected header %v err=%v", header, err)
+	}
+	var payloads []Payload
+	if err := json.Unmarshal(body, &payloads); err != nil || len(payloads) != 2 {
+		t.Fatalf("json: expected array of 2, got %s err=%v", body, err)
+	}
+
+	for _, format := range []string{FormatCloudEvents, FormatCloudEventsBinary} {
+		body, header, err := encodeBatch(format, DefaultSource, deliveries)
+		if err != nil || header.Get("Content-Type") != "application/cloudevents-batch+json" {
+			t.Fatalf("%s: unexpected header %v err=%v", format, header, err)
+		}
+		var events []CloudEvent
+		if err := json.Unmarshal(body, &events); err != nil || len(events) != 2 || events[1].ID != "b" || events[1].Type != "message.receipt" {
+			t.Fatalf("%s: unexpected batch %s err=%v", format, body, err)
+		}
+	}
+}
@@ -0,0 +1,98 @@
+package webhook
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// Payload formats. FormatJSON is the gateway's own envelope; the CloudEvents
+// formats follow the CloudEvents 1.0 HTTP binding in structured mode (the
+// whole event as the body) or binary mode (attributes as ce-* headers, data
+// as the body).
+const (
+	FormatJSON              = "json"
+	FormatCloudEvents       = "cloudevents"
+	FormatCloudEventsBinary = "cloudevents-binary"
+
+	// DefaultSource is the CloudEvents source when none is configured
+	DefaultSource = "wa-gateway-go"
+)
+
+// ValidFormat reports whether f is a known payload format.
+func ValidFormat(f string) bool {
+	return f == FormatJSON || f == FormatCloudEvents || f == FormatCloudEventsBinary
+}
+
+// CloudEvent is a CloudEvents 1.0 event in structured mode. ID is the
+// delivery's X-Webhook-Id and Subject the device token.
+type CloudEvent struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            string          `json:"time"`
+	Subject         string          `json:"subject"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// encode renders a stored payload in format, returning the request body and
+// the headers that describe it.
+func encode(format, source string, delivery Delivery) ([]byte, http.Header, error) {
+	header := http.Header{}
+	if format != FormatCloudEvents && format != FormatCloudEventsBinary {
+		header.Set("Content-Type", "application/json")
+		return delivery.Payload, header, nil
+	}
+
+	ce, err := cloudEvent(source, delivery)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if format == FormatCloudEventsBinary {
+		header.Set("Content-Type", ce.DataContentType)
+		header.Set("ce-specversion", ce.SpecVersion)
+		header.Set("ce-id", ce.ID)
+		header.Set("ce-source", ce.Source)
+		header.Set("ce-type", ce.Type)
+		header.Set("ce-time", ce.Time)
+		header.Set("ce-subject", ce.Subject)
+		return ce.Data, header, nil
+	}
+
+	body, err := json.Marshal(ce)
+	if err != nil {
+		return nil, nil, err
+	}
+	header.Set("Content-Type", "application/cloudevents+json")
+	return body, header, nil
+}
+
+// cloudEvent converts a stored payload to a CloudEvent.
+func cloudEvent(source string, delivery Delivery) (CloudEvent, error) {
+	var p struct {
+		Event     string          `json:"event"`
+		Token     string          `json:"token"`
+		Data      json.RawMessage `json:"data"`
+		Timestamp string          `json:"timestamp"`
+	}
+	if err := json.Unmarshal(delivery.Payload, &p); err != nil {
+		return CloudEvent{}, fmt.Errorf("invalid stored payload: %w", err)
+	}
+	if len(p.Data) == 0 {
+		p.Data = json.RawMessage("null")
+	}
+
+	return CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              delivery.WebhookID,
+		Source:          source,
+		Type:            p.Event,
+		Time:            p.Timestamp,
+		Subject:         p.Token,
+		DataContentType: "application/json",
+		Data:            p.Data,
+	}, nil
+}
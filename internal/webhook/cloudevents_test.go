@@ -0,0 +1,79 @@
+package webhook
+
+import (
+	"encoding/json"
+	"testing"
+)
+
+func TestEncode(t *testing.T) {
+	delivery := Delivery{
+		WebhookID: "0b6c6f0e-3f0c-4a51-9d4b-0a4c1f7e2d11",
+		Payload:   []byte(`{"event":"message.received","token":"60123456789","data":{"id":"ABC"},"timestamp":"2026-02-17T10:30:00Z"}`),
+	}
+
+	body, header, err := encode(FormatJSON, DefaultSource, delivery)
+	if err != nil || string(body) != string(delivery.Payload) || header.Get("Content-Type") != "application/json" {
+		t.Fatalf("json: unexpected body %s header %v err=%v", body, header, err)
+	}
+
+	body, header, err = encode(FormatCloudEvents, "/gateways/eu-1", delivery)
+	if err != nil {
+		t.Fatalf("structured: %v", err)
+	}
+	if header.Get("Content-Type") != "application/cloudevents+json" {
+		t.Errorf("structured: unexpected content type %q", header.Get("Content-Type"))
+	}
+	var ce CloudEvent
+	if err := json.Unmarshal(body, &ce); err != nil {
+		t.Fatalf("structured: invalid body %s: %v", body, err)
+	}
+	want := CloudEvent{
+		SpecVersion:     "1.0",
+		ID:              delivery.WebhookID,
+		Source:          "/gateways/eu-1",
+		Type:            "message.received",
+		Time:            "2026-02-17T10:30:00Z",
+		Subject:         "60123456789",
+		DataContentType: "application/json",
+		Data:            json.RawMessage(`{"id":"ABC"}`),
+	}
+	if ce.SpecVersion != want.SpecVersion || ce.ID != want.ID || ce.Source != want.Source || ce.Type != want.Type ||
+		ce.Time != want.Time || ce.Subject != want.Subject || ce.DataContentType != want.DataContentType || string(ce.Data) != string(want.Data) {
+		t.Errorf("structured: got %+v, want %+v", ce, want)
+	}
+
+	body, header, err = encode(FormatCloudEventsBinary, DefaultSource, delivery)
+	if err != nil {
+		t.Fatalf("binary: %v", err)
+	}
+	if string(body) != `{"id":"ABC"}` {
+		t.Errorf("binary: expected data as body, got %s", body)
+	}
+	headers := map[string]string{
+		"Content-Type":   "application/json",
+		"ce-specversion": "1.0",
+		"ce-id":          delivery.WebhookID,
+		"ce-source":      DefaultSource,
+		"ce-type":        "message.received",
+		"ce-time":        "2026-02-17T10:30:00Z",
+		"ce-subject":     "60123456789",
+	}
+	for k, v := range headers {
+		if got := header.Get(k); got != v {
+			t.Errorf("binary: header %s = %q, want %q", k, got, v)
+		}
+	}
+}
+
+func TestSubscription_ValidateFormat(t *testing.T) {
+	for _, format := range []string{"", FormatJSON, FormatCloudEvents, FormatCloudEventsBinary} {
+		sub := Subscription{URL: "https://example.com/hook", Format: format}
+		if err := sub.Validate(); err != nil {
+			t.Errorf("format %q: unexpected error %v", format, err)
+		}
+	}
+	sub := Subscription{URL: "https://example.com/hook", Format: "xml"}
+	if err := sub.Validate(); err == nil {
+		t.Error("expected error for unknown format")
+	}
+}
@@ -0,0 +1,135 @@
+package webhook
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+)
+
+// DeliveryAttempt is one logged HTTP attempt for a delivery.
+type DeliveryAttempt struct {
+	ID             int64           `json:"id"`
+	WebhookID      string          `json:"webhookId"`
+	Event          string          `json:"event"`
+	Token          string          `json:"token"`
+	SubscriptionID string          `json:"subscriptionId,omitempty"`
+	URL            string          `json:"url"`
+	Payload        json.RawMessage `json:"payload"`
+	Attempt        int             `json:"attempt"`
+	Success        bool            `json:"success"`
+	StatusCode     int             `json:"statusCode"`
+	LatencyMs      int64           `json:"latencyMs"`
+	Response       string          `json:"response"`
+	Error          string          `json:"error"`
+	CreatedAt      string          `json:"createdAt"`
+}
+
+// AttemptFilter narrows the delivery log. Event accepts the same patterns as
+// subscriptions; Status is "success", "failed", or empty for both.
+type AttemptFilter struct {
+	Event  string
+	Token  string
+	Status string
+	Limit  int
+	Offset int
+}
+
+// RecordAttempt appends an attempt to the delivery log.
+func (o *Outbox) RecordAttempt(a *DeliveryAttempt) error {
+	a.CreatedAt = time.Now().UTC().Format(time.RFC3339)
+	res, err := o.db.Exec(`
+		INSERT INTO delivery_attempts (webhook_id, event, token, subscription_id, url, payload, attempt, success, status_code, latency_ms, response, error, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, a.WebhookID, a.Event, a.Token, a.SubscriptionID, a.URL, []byte(a.Payload), a.Attempt, a.Success, a.StatusCode, a.LatencyMs, a.Response, a.Error, a.CreatedAt)
+	if err != nil {
+		return err
+	}
+	a.ID, err = res.LastInsertId()
+	return err
+}
+
+// Attempts lists logged attempts matching the filter, newest first.
+func (o *Outbox) Attempts(f AttemptFilter) ([]DeliveryAttempt, int, error) {
+	var conds []string
+	var args []interface{}
+	if f.Event != "" {
+		// GLOB treats "*" like MatchEvent; event names never contain "?" or "["
+		conds = append(conds, "event GLOB ?")
+		args = append(args, f.Event)
+	}
+	if f.Token != "" {
+		conds = append(conds, "token = ?")
+		args = append(args, f.Token)
+	}
+	switch f.Status {
+	case "success":
+		conds = append(conds, "success = 1")
+	case "failed":
+		conds = append(conds, "success = 0")
+	}
+
+	where := ""
+	if len(conds) > 0 {
+		where = " WHERE " + strings.Join(conds, " AND ")
+	}
+
+	var total int
+	if err := o.db.QueryRow("SELECT COUNT(*) FROM delivery_attempts"+where, args...).Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := o.db.Query(`
+		SELECT id, webhook_id, event, token, subscription_id, url, payload, attempt, success, status_code, latency_ms, response, error, created_at
+		FROM delivery_attempts`+where+` ORDER BY id DESC LIMIT ? OFFSET ?
+	`, append(args, f.Limit, f.Offset)...)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	attempts := []DeliveryAttempt{}
+	for rows.Next() {
+		var a DeliveryAttempt
+		var payload []byte
+		if err := rows.Scan(&a.ID, &a.WebhookID, &a.Event, &a.Token, &a.SubscriptionID, &a.URL, &payload, &a.Attempt, &a.Success, &a.StatusCode, &a.LatencyMs, &a.Response, &a.Error, &a.CreatedAt); err != nil {
+			return nil, 0, err
+		}
+		a.Payload = payload
+		attempts = append(attempts, a)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return attempts, total, nil
+}
+
+// Redeliver queues the logged attempt's payload again with the same webhook
+// ID and returns the new outbox ID. Redeliveries are delivered unordered.
+func (o *Outbox) Redeliver(id int64) (int64, error) {
+	now := time.Now().UTC()
+	res, err := o.db.Exec(`
+		INSERT INTO outbox (webhook_id, event, token, subscription_id, url, payload, next_attempt_at, created_at)
+		SELECT webhook_id, event, token, subscription_id, url, payload, ?, ? FROM delivery_attempts WHERE id = ?
+	`, now.UnixMilli(), now.Format(time.RFC3339), id)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrNotFound
+	}
+	return res.LastInsertId()
+}
+
+// PruneAttempts deletes logged attempts older than before.
+func (o *Outbox) PruneAttempts(before time.Time) (int64, error) {
+	res, err := o.db.Exec("DELETE FROM delivery_attempts WHERE created_at < ?", before.UTC().Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
@@ -0,0 +1,61 @@
+package webhook
+
+import (
+	"fmt"
+
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+// NATSSink publishes events to a NATS server. Messages carry a Nats-Msg-Id
+// header so JetStream streams can drop duplicates.
+type NATSSink struct {
+	conn     *nats.Conn
+	subjects *Subjects
+	closed   chan struct{}
+}
+
+// NewNATSSink connects to url. The gateway starts even if the server is down;
+// the client keeps reconnecting and buffers messages meanwhile.
+func NewNATSSink(url string, subjects *Subjects, logger zerolog.Logger) (*NATSSink, error) {
+	logger = logger.With().Str("component", "nats").Logger()
+	closed := make(chan struct{})
+	conn, err := nats.Connect(url,
+		nats.Name("wa-gateway-go"),
+		nats.RetryOnFailedConnect(true),
+		nats.MaxReconnects(-1),
+		nats.DisconnectErrHandler(func(_ *nats.Conn, err error) {
+			logger.Warn().Err(err).Msg("disconnected from NATS")
+		}),
+		nats.ReconnectHandler(func(c *nats.Conn) {
+			logger.Info().Str("url", c.ConnectedUrlRedacted()).Msg("reconnected to NATS")
+		}),
+		nats.ClosedHandler(func(*nats.Conn) { close(closed) }),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("failed to connect to NATS: %w", err)
+	}
+	return &NATSSink{conn: conn, subjects: subjects, closed: closed}, nil
+}
+
+func (s *NATSSink) Name() string {
+	return "nats"
+}
+
+func (s *NATSSink) Publish(event, token, id string, payload []byte) error {
+	msg := nats.NewMsg(s.subjects.For(event, token))
+	msg.Header.Set(nats.MsgIdHdr, id)
+	msg.Header.Set("Content-Type", "application/json")
+	msg.Data = payload
+	return s.conn.PublishMsg(msg)
+}
+
+// Close flushes buffered messages and waits for the connection to close.
+func (s *NATSSink) Close() error {
+	if err := s.conn.Drain(); err != nil {
+		// Not connected, so nothing can be flushed
+		s.conn.Close()
+	}
+	<-s.closed
+	return nil
+}
@@ -0,0 +1,105 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/rs/zerolog"
+)
+
+func startNATSServer(t *testing.T) *server.Server {
+	t.Helper()
+	ns, err := server.NewServer(&server.Options{Host: "127.0.0.1", Port: -1, NoLog: true, NoSigs: true})
+	if err != nil {
+		t.Fatalf("failed to create NATS server: %v", err)
+	}
+	go ns.Start()
+	if !ns.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server not ready")
+	}
+	t.Cleanup(ns.Shutdown)
+	return ns
+}
+
+func TestDispatcher_NATSSink(t *testing.T) {
+	ns := startNATSServer(t)
+
+	var hits atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+	}))
+	defer srv.Close()
+
+	sub, err := nats.Connect(ns.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect: %v", err)
+	}
+	defer sub.Close()
+	msgs := make(chan *nats.Msg, 10)
+	if _, err := sub.ChanSubscribe("wa.>", msgs); err != nil {
+		t.Fatalf("failed to subscribe: %v", err)
+	}
+	if err := sub.Flush(); err != nil {
+		t.Fatalf("failed to flush: %v", err)
+	}
+
+	subjects, err := ParseSubjects("wa.{event}.{token}", "message.*=wa.messages")
+	if err != nil {
+		t.Fatalf("failed to parse subjects: %v", err)
+	}
+	sink, err := NewNATSSink(ns.ClientURL(), subjects, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to create sink: %v", err)
+	}
+	d, err := NewDispatcher(Config{
+		URL:         srv.URL,
+		Timeout:     time.Second,
+		MaxAttempts: 1,
+		Sinks:       []Sink{sink},
+		DisableHTTP: true,
+		DBPath:      filepath.Join(t.TempDir(), "webhooks.db"),
+	}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	d.Start()
+	defer d.Close()
+
+	d.Send("message.received", "dev1", map[string]string{"from": "60123456789"})
+	d.Send("device.connected", "dev1", nil)
+
+	want := []string{"wa.messages", "wa.device.connected.dev1"}
+	for _, subject := range want {
+		select {
+		case msg := <-msgs:
+			if msg.Subject != subject {
+				t.Errorf("expected subject %s, got %s", subject, msg.Subject)
+			}
+			if msg.Header.Get(nats.MsgIdHdr) == "" {
+				t.Error("expected Nats-Msg-Id header")
+			}
+			var p Payload
+			if err := json.Unmarshal(msg.Data, &p); err != nil {
+				t.Fatalf("invalid payload: %v", err)
+			}
+			if p.Token != "dev1" {
+				t.Errorf("expected token dev1, got %s", p.Token)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", subject)
+		}
+	}
+
+	time.Sleep(100 * time.Millisecond)
+	if n := hits.Load(); n != 0 {
+		t.Errorf("expected no HTTP deliveries with DisableHTTP, got %d", n)
+	}
+}
@@ -0,0 +1,280 @@
+package webhook
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/google/uuid"
+
+	_ "modernc.org/sqlite"
+)
+
+var ErrNotFound = errors.New("not found")
+
+// Delivery is a webhook request waiting in the outbox. WebhookID is sent as
+// X-Webhook-Id and stays the same across retries and replays. Deliveries
+// sharing a non-empty OrderKey are attempted one at a time, oldest first, and
+// deliveries sharing a BatchID are sent together in one request.
+type Delivery struct {
+	ID             int64
+	WebhookID      string
+	BatchID        string
+	Event          string
+	Token          string
+	SubscriptionID string
+	URL            string
+	OrderKey       string
+	Payload        []byte
+	Attempts       int
+	NextAttemptAt  time.Time
+}
+
+// DeadLetter is a delivery that exhausted its attempts or was rejected.
+type DeadLetter struct {
+	ID             int64           `json:"id"`
+	WebhookID      string          `json:"webhookId"`
+	Event          string          `json:"event"`
+	Token          string          `json:"token"`
+	SubscriptionID string          `json:"subscriptionId,omitempty"`
+	URL            string          `json:"url"`
+	Payload        json.RawMessage `json:"payload"`
+	Attempts       int             `json:"attempts"`
+	LastError      string          `json:"lastError"`
+	CreatedAt      string          `json:"createdAt"`
+	FailedAt       string          `json:"failedAt"`
+}
+
+// Outbox persists pending deliveries, dead letters and the delivery attempt
+// log so events survive receiver outages and restarts.
+type Outbox struct {
+	db *sql.DB
+}
+
+func NewOutbox(dbPath string) (*Outbox, error) {
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS outbox (
+			id              INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id      TEXT NOT NULL,
+			event           TEXT NOT NULL,
+			token           TEXT NOT NULL,
+			subscription_id TEXT DEFAULT '',
+			url             TEXT NOT NULL,
+			order_key       TEXT DEFAULT '',
+			batch_id        TEXT DEFAULT '',
+			payload         BLOB NOT NULL,
+			attempts        INTEGER DEFAULT 0,
+			next_attempt_at INTEGER NOT NULL,
+			last_error      TEXT DEFAULT '',
+			created_at      DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE INDEX IF NOT EXISTS idx_outbox_next ON outbox(next_attempt_at);
+		CREATE INDEX IF NOT EXISTS idx_outbox_order ON outbox(order_key, id);
+		CREATE INDEX IF NOT EXISTS idx_outbox_batch ON outbox(batch_id);
+		CREATE TABLE IF NOT EXISTS dead_letters (
+			id              INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id      TEXT NOT NULL,
+			event           TEXT NOT NULL,
+			token           TEXT NOT NULL,
+			subscription_id TEXT DEFAULT '',
+			url             TEXT NOT NULL,
+			payload         BLOB NOT NULL,
+			attempts        INTEGER DEFAULT 0,
+			last_error      TEXT DEFAULT '',
+			created_at      DATETIME,
+			failed_at       DATETIME DEFAULT CURRENT_TIMESTAMP
+		);
+		CREATE TABLE IF NOT EXISTS delivery_attempts (
+			id              INTEGER PRIMARY KEY AUTOINCREMENT,
+			webhook_id      TEXT NOT NULL,
+			event           TEXT NOT NULL,
+			token           TEXT NOT NULL,
+			subscription_id TEXT DEFAULT '',
+			url             TEXT NOT NULL,
+			payload         BLOB NOT NULL,
+			attempt         INTEGER NOT NULL,
+			success         INTEGER NOT NULL,
+			status_code     INTEGER DEFAULT 0,
+			latency_ms      INTEGER DEFAULT 0,
+			response        TEXT DEFAULT '',
+			error           TEXT DEFAULT '',
+			created_at      DATETIME NOT NULL
+		);
+		CREATE INDEX IF NOT EXISTS idx_delivery_attempts_created ON delivery_attempts(created_at)
+	`)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Outbox{db: db}, nil
+}
+
+// Enqueue stores a delivery that is due immediately. subscriptionID is empty
+// for the global WEBHOOK_URL and orderKey is empty for unordered delivery.
+func (o *Outbox) Enqueue(event, token, subscriptionID, url, orderKey string, payload []byte) (int64, error) {
+	now := time.Now().UTC()
+	res, err := o.db.Exec(`
+		INSERT INTO outbox (webhook_id, event, token, subscription_id, url, order_key, payload, next_attempt_at, created_at)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`, uuid.New().String(), event, token, subscriptionID, url, orderKey, payload, now.UnixMilli(), now.Format(time.RFC3339))
+	if err != nil {
+		return 0, err
+	}
+	return res.LastInsertId()
+}
+
+// Due returns up to limit deliveries whose next attempt is at or before now,
+// oldest first. Only the oldest delivery of each order key is returned, so a
+// failing delivery holds back the rest of its queue until it is delivered or
+// dead-lettered.
+func (o *Outbox) Due(now time.Time, limit int) ([]Delivery, error) {
+	rows, err := o.db.Query(`
+		SELECT `+deliveryColumns+`
+		FROM outbox o
+		WHERE next_attempt_at <= ?
+			AND (order_key = '' OR id = (SELECT MIN(id) FROM outbox WHERE order_key = o.order_key))
+		ORDER BY next_attempt_at, id LIMIT ?
+	`, now.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanDeliveries(rows)
+}
+
+const deliveryColumns = "id, webhook_id, batch_id, event, token, subscription_id, url, order_key, payload, attempts, next_attempt_at"
+
+func scanDeliveries(rows *sql.Rows) ([]Delivery, error) {
+	defer func() { _ = rows.Close() }()
+
+	var deliveries []Delivery
+	for rows.Next() {
+		var d Delivery
+		var next int64
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.BatchID, &d.Event, &d.Token, &d.SubscriptionID, &d.URL, &d.OrderKey, &d.Payload, &d.Attempts, &next); err != nil {
+			return nil, err
+		}
+		d.NextAttemptAt = time.UnixMilli(next).UTC()
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// Pending counts deliveries still waiting in the outbox.
+func (o *Outbox) Pending() (int, error) {
+	var n int
+	err := o.db.QueryRow("SELECT COUNT(*) FROM outbox").Scan(&n)
+	return n, err
+}
+
+// Complete removes a delivered entry.
+func (o *Outbox) Complete(id int64) error {
+	_, err := o.db.Exec("DELETE FROM outbox WHERE id = ?", id)
+	return err
+}
+
+// Retry records a failed attempt and schedules the next one.
+func (o *Outbox) Retry(id int64, attempts int, next time.Time, lastErr string) error {
+	_, err := o.db.Exec(
+		"UPDATE outbox SET attempts = ?, next_attempt_at = ?, last_error = ? WHERE id = ?",
+		attempts, next.UnixMilli(), lastErr, id,
+	)
+	return err
+}
+
+// Kill moves an entry from the outbox to the dead-letter table.
+func (o *Outbox) Kill(id int64, attempts int, lastErr string) error {
+	tx, err := o.db.Begin()
+	if err != nil {
+		return err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	_, err = tx.Exec(`
+		INSERT INTO dead_letters (webhook_id, event, token, subscription_id, url, payload, attempts, last_error, created_at, failed_at)
+		SELECT webhook_id, event, token, subscription_id, url, payload, ?, ?, created_at, ? FROM outbox WHERE id = ?
+	`, attempts, lastErr, time.Now().UTC().Format(time.RFC3339), id)
+	if err != nil {
+		return err
+	}
+	if _, err := tx.Exec("DELETE FROM outbox WHERE id = ?", id); err != nil {
+		return err
+	}
+	return tx.Commit()
+}
+
+// DeadLetters lists dead letters, newest first.
+func (o *Outbox) DeadLetters(limit, offset int) ([]DeadLetter, int, error) {
+	var total int
+	if err := o.db.QueryRow("SELECT COUNT(*) FROM dead_letters").Scan(&total); err != nil {
+		return nil, 0, err
+	}
+
+	rows, err := o.db.Query(`
+		SELECT id, webhook_id, event, token, subscription_id, url, payload, attempts, last_error, created_at, failed_at
+		FROM dead_letters ORDER BY id DESC LIMIT ? OFFSET ?
+	`, limit, offset)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	letters := []DeadLetter{}
+	for rows.Next() {
+		var d DeadLetter
+		var payload []byte
+		if err := rows.Scan(&d.ID, &d.WebhookID, &d.Event, &d.Token, &d.SubscriptionID, &d.URL, &payload, &d.Attempts, &d.LastError, &d.CreatedAt, &d.FailedAt); err != nil {
+			return nil, 0, err
+		}
+		d.Payload = payload
+		letters = append(letters, d)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, 0, err
+	}
+
+	return letters, total, nil
+}
+
+// Replay moves a dead letter back into the outbox with a fresh attempt
+// count and returns the new outbox ID. Replays are delivered unordered.
+func (o *Outbox) Replay(id int64) (int64, error) {
+	tx, err := o.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer func() { _ = tx.Rollback() }()
+
+	res, err := tx.Exec(`
+		INSERT INTO outbox (webhook_id, event, token, subscription_id, url, payload, next_attempt_at, created_at)
+		SELECT webhook_id, event, token, subscription_id, url, payload, ?, created_at FROM dead_letters WHERE id = ?
+	`, time.Now().UnixMilli(), id)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+	if n == 0 {
+		return 0, ErrNotFound
+	}
+	newID, err := res.LastInsertId()
+	if err != nil {
+		return 0, err
+	}
+	if _, err := tx.Exec("DELETE FROM dead_letters WHERE id = ?", id); err != nil {
+		return 0, err
+	}
+	return newID, tx.Commit()
+}
+
+func (o *Outbox) Close() error {
+	return o.db.Close()
+}
@@ -0,0 +1,176 @@
+package webhook
+
+import (
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func newTestOutbox(t *testing.T) *Outbox {
+	t.Helper()
+	o, err := NewOutbox(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err != nil {
+		t.Fatalf("failed to create outbox: %v", err)
+	}
+	t.Cleanup(func() { _ = o.Close() })
+	return o
+}
+
+func TestOutbox_RetryKillReplay(t *testing.T) {
+	o := newTestOutbox(t)
+
+	id, err := o.Enqueue("device.connected", "60123456789", "", "http://example.com", "", []byte(`{"event":"device.connected"}`))
+	if err != nil {
+		t.Fatalf("enqueue failed: %v", err)
+	}
+
+	due, err := o.Due(time.Now(), 10)
+	if err != nil || len(due) != 1 || due[0].ID != id {
+		t.Fatalf("expected entry to be due, got %+v err=%v", due, err)
+	}
+
+	if err := o.Retry(id, 1, time.Now().Add(time.Hour), "status 503"); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	if due, _ := o.Due(time.Now(), 10); len(due) != 0 {
+		t.Fatalf("expected rescheduled entry not to be due, got %+v", due)
+	}
+	if due, _ := o.Due(time.Now().Add(2*time.Hour), 10); len(due) != 1 || due[0].Attempts != 1 {
+		t.Fatalf("expected entry due later with 1 attempt, got %+v", due)
+	}
+
+	if err := o.Kill(id, 2, "status 400"); err != nil {
+		t.Fatalf("kill failed: %v", err)
+	}
+	if n, _ := o.Pending(); n != 0 {
+		t.Fatalf("expected empty outbox, got %d", n)
+	}
+
+	letters, total, err := o.DeadLetters(10, 0)
+	if err != nil || total != 1 || len(letters) != 1 {
+		t.Fatalf("expected one dead letter, got total=%d %+v err=%v", total, letters, err)
+	}
+	dl := letters[0]
+	if dl.Attempts != 2 || dl.LastError != "status 400" || string(dl.Payload) != `{"event":"device.connected"}` {
+		t.Fatalf("unexpected dead letter: %+v", dl)
+	}
+
+	if _, err := o.Replay(dl.ID); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	if _, err := o.Replay(dl.ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound on second replay, got %v", err)
+	}
+	due, _ = o.Due(time.Now(), 10)
+	if len(due) != 1 || due[0].Attempts != 0 || due[0].URL != "http://example.com" {
+		t.Fatalf("expected replayed entry with fresh attempts, got %+v", due)
+	}
+	if _, total, _ := o.DeadLetters(10, 0); total != 0 {
+		t.Fatalf("expected no dead letters after replay, got %d", total)
+	}
+}
+
+func TestOutbox_AttemptFiltersAndPrune(t *testing.T) {
+	o := newTestOutbox(t)
+
+	log := []DeliveryAttempt{
+		{Event: "message.receipt", Token: "60123456789", Success: true, StatusCode: 200},
+		{Event: "message.received", Token: "60198765432", Success: false, StatusCode: 500},
+		{Event: "device.connected", Token: "60123456789", Success: false, Error: "connection refused"},
+	}
+	for i := range log {
+		log[i].WebhookID = "id"
+		log[i].URL = "http://example.com"
+		log[i].Payload = []byte(`{}`)
+		log[i].Attempt = 1
+		if err := o.RecordAttempt(&log[i]); err != nil {
+			t.Fatalf("record failed: %v", err)
+		}
+	}
+
+	tests := []struct {
+		name   string
+		filter AttemptFilter
+		want   int
+	}{
+		{"all", AttemptFilter{}, 3},
+		{"event", AttemptFilter{Event: "device.connected"}, 1},
+		{"event wildcard", AttemptFilter{Event: "message.*"}, 2},
+		{"token", AttemptFilter{Token: "60123456789"}, 2},
+		{"success", AttemptFilter{Status: "success"}, 1},
+		{"failed for token", AttemptFilter{Status: "failed", Token: "60123456789"}, 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.filter.Limit = 10
+			got, total, err := o.Attempts(tt.filter)
+			if err != nil || total != tt.want || len(got) != tt.want {
+				t.Fatalf("got %d (total %d) err=%v, want %d", len(got), total, err, tt.want)
+			}
+		})
+	}
+
+	if n, err := o.PruneAttempts(time.Now().Add(-time.Hour)); err != nil || n != 0 {
+		t.Fatalf("expected nothing pruned, got %d err=%v", n, err)
+	}
+	if n, err := o.PruneAttempts(time.Now().Add(time.Hour)); err != nil || n != 3 {
+		t.Fatalf("expected 3 pruned, got %d err=%v", n, err)
+	}
+}
+
+func TestOutbox_DueOrderedHeads(t *testing.T) {
+	o := newTestOutbox(t)
+
+	enqueue := func(event, key string) int64 {
+		t.Helper()
+		id, err := o.Enqueue(event, "60123456789", "", "http://example.com", key, []byte(`{}`))
+		if err != nil {
+			t.Fatalf("enqueue failed: %v", err)
+		}
+		return id
+	}
+	a1 := enqueue("a1", "|a")
+	a2 := enqueue("a2", "|a")
+	enqueue("b1", "|b")
+	enqueue("u1", "")
+	enqueue("u2", "")
+
+	events := func(now time.Time) []string {
+		due, err := o.Due(now, 10)
+		if err != nil {
+			t.Fatalf("due failed: %v", err)
+		}
+		var got []string
+		for _, d := range due {
+			got = append(got, d.Event)
+		}
+		return got
+	}
+	assertEvents := func(got []string, want ...string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("got %v, want %v", got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Fatalf("got %v, want %v", got, want)
+			}
+		}
+	}
+
+	assertEvents(events(time.Now()), "a1", "b1", "u1", "u2")
+
+	// A head waiting to retry holds back the rest of its queue
+	if err := o.Retry(a1, 1, time.Now().Add(time.Hour), "status 503"); err != nil {
+		t.Fatalf("retry failed: %v", err)
+	}
+	assertEvents(events(time.Now()), "b1", "u1", "u2")
+
+	if err := o.Kill(a1, 2, "status 503"); err != nil {
+		t.Fatalf("kill failed: %v", err)
+	}
+	due, _ := o.Due(time.Now(), 10)
+	if len(due) == 0 || due[0].ID != a2 {
+		t.Fatalf("expected a2 to be due after a1 was dead-lettered, got %+v", due)
+	}
+}
@@ -0,0 +1,66 @@
+package webhook
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Sink receives every event the dispatcher sends, in addition to (or instead
+// of) HTTP webhooks. Publish gets the event in the default JSON format and a
+// unique event ID; it must not block for long, as it runs on the caller of
+// Send.
+type Sink interface {
+	Name() string
+	Publish(event, token, id string, payload []byte) error
+	Close() error
+}
+
+// Subjects maps events to broker subjects or routing keys. Templates may use
+// {event} and {token}; rules are checked in order before the default.
+type Subjects struct {
+	def   string
+	rules []subjectRule
+}
+
+type subjectRule struct {
+	pattern  string
+	template string
+}
+
+// ParseSubjects builds a mapping from a default template and a
+// comma-separated list of pattern=template overrides, such as
+// "message.*=wa.messages.{token},device.*=wa.devices".
+func ParseSubjects(def, overrides string) (*Subjects, error) {
+	if def == "" {
+		return nil, fmt.Errorf("default subject is required")
+	}
+	s := &Subjects{def: def}
+	for _, entry := range strings.Split(overrides, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		pattern, template, ok := strings.Cut(entry, "=")
+		pattern, template = strings.TrimSpace(pattern), strings.TrimSpace(template)
+		if !ok || template == "" {
+			return nil, fmt.Errorf("invalid subject override %q: expected pattern=subject", entry)
+		}
+		if !eventPatternRegex.MatchString(pattern) {
+			return nil, fmt.Errorf("invalid event pattern %q", pattern)
+		}
+		s.rules = append(s.rules, subjectRule{pattern: pattern, template: template})
+	}
+	return s, nil
+}
+
+// For returns the subject for an event from a device.
+func (s *Subjects) For(event, token string) string {
+	template := s.def
+	for _, r := range s.rules {
+		if MatchEvent(r.pattern, event) {
+			template = r.template
+			break
+		}
+	}
+	return strings.NewReplacer("{event}", event, "{token}", token).Replace(template)
+}
@@ -0,0 +1,40 @@
+package webhook
+
+import "testing"
+
+func TestParseSubjects(t *testing.T) {
+	s, err := ParseSubjects("wa.{event}.{token}", "message.*=wa.messages.{token}, device.connected=wa.up")
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	tests := []struct {
+		event string
+		want  string
+	}{
+		{"message.received", "wa.messages.abc"},
+		{"device.connected", "wa.up"},
+		{"device.disconnected", "wa.device.disconnected.abc"},
+	}
+	for _, tt := range tests {
+		if got := s.For(tt.event, "abc"); got != tt.want {
+			t.Errorf("For(%q) = %q, want %q", tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestParseSubjects_Invalid(t *testing.T) {
+	tests := []struct {
+		def, overrides string
+	}{
+		{"", ""},
+		{"wa.{event}", "message.*"},
+		{"wa.{event}", "message.*="},
+		{"wa.{event}", "bad pattern=wa.x"},
+	}
+	for _, tt := range tests {
+		if _, err := ParseSubjects(tt.def, tt.overrides); err == nil {
+			t.Errorf("ParseSubjects(%q, %q): expected error", tt.def, tt.overrides)
+		}
+	}
+}
@@ -0,0 +1,235 @@
+package webhook
+
+import (
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/webhooksig"
+
+	_ "modernc.org/sqlite"
+)
+
+// eventPatternRegex accepts an event name, a prefix wildcard such as
+// "message.*", or "*" for every event.
+var eventPatternRegex = regexp.MustCompile(`^(\*|[a-z_]+(\.[a-z_]+)*(\.\*)?)$`)
+
+// Subscription is a registered webhook endpoint. Empty Events or Tokens
+// match every event or device, and an empty Format uses WEBHOOK_FORMAT.
+type Subscription struct {
+	ID        string   `json:"id"`
+	URL       string   `json:"url"`
+	Secret    string   `json:"secret,omitempty"`
+	HasSecret bool     `json:"hasSecret"`
+	Events    []string `json:"events"`
+	Tokens    []string `json:"tokens"`
+	Format    string   `json:"format,omitempty"`
+	Enabled   bool     `json:"enabled"`
+	CreatedAt string   `json:"createdAt"`
+	UpdatedAt string   `json:"updatedAt"`
+
+	// secrets replaces Secret for the global endpoint, whose secrets are
+	// configured as a list
+	secrets []string
+}
+
+// signingSecrets returns the secrets requests to the endpoint are signed
+// with. Secret may be a comma-separated list during rotation.
+func (s Subscription) signingSecrets() []string {
+	if s.secrets != nil {
+		return s.secrets
+	}
+	return webhooksig.ParseSecrets(s.Secret)
+}
+
+func (s *Subscription) Validate() error {
+	if err := validator.ValidateURL(s.URL); err != nil {
+		return err
+	}
+	if s.Format != "" && !ValidFormat(s.Format) {
+		return fmt.Errorf("format must be %q, %q or %q", FormatJSON, FormatCloudEvents, FormatCloudEventsBinary)
+	}
+	for _, e := range s.Events {
+		if !eventPatternRegex.MatchString(e) {
+			return fmt.Errorf("invalid event pattern %q", e)
+		}
+	}
+	for _, t := range s.Tokens {
+		if err := validator.ValidateToken(t); err != nil {
+			return fmt.Errorf("invalid token %q: must be 7-15 digits", t)
+		}
+	}
+	return nil
+}
+
+// Matches reports whether the subscription wants the event for the device.
+func (s *Subscription) Matches(event, token string) bool {
+	if !s.Enabled {
+		return false
+	}
+	if len(s.Tokens) > 0 && !contains(s.Tokens, token) {
+		return false
+	}
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, pattern := range s.Events {
+		if MatchEvent(pattern, event) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns a copy without the secret, for API responses.
+func (s Subscription) Redacted() Subscription {
+	s.Secret = ""
+	return s
+}
+
+// MatchEvent reports whether event matches pattern: an exact name, "*", or a
+// prefix wildcard ("message.*" matches "message.receipt").
+func MatchEvent(pattern, event string) bool {
+	if pattern == "*" {
+		return true
+	}
+	if prefix, ok := strings.CutSuffix(pattern, "*"); ok {
+		return strings.HasPrefix(event, prefix)
+	}
+	return pattern == event
+}
+
+func contains(list []string, v string) bool {
+	for _, item := range list {
+		if item == v {
+			return true
+		}
+	}
+	return false
+}
+
+// Subscriptions persists the webhook subscription registry.
+type Subscriptions struct {
+	db *sql.DB
+}
+
+func NewSubscriptions(dbPath string) (*Subscriptions, error) {
+	db, err := sql.Open("sqlite", dbPath+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = db.Exec(`
+		CREATE TABLE IF NOT EXISTS subscriptions (
+			id         TEXT PRIMARY KEY,
+			data       TEXT NOT NULL,
+			created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+		)
+	`)
+	if err != nil {
+		_ = db.Close()
+		return nil, err
+	}
+
+	return &Subscriptions{db: db}, nil
+}
+
+// List returns all subscriptions in creation order.
+func (s *Subscriptions) List() ([]Subscription, error) {
+	rows, err := s.db.Query("SELECT data FROM subscriptions ORDER BY created_at ASC, id ASC")
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+
+	subs := []Subscription{}
+	for rows.Next() {
+		var data string
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var sub Subscription
+		if err := json.Unmarshal([]byte(data), &sub); err != nil {
+			return nil, err
+		}
+		subs = append(subs, sub)
+	}
+	return subs, rows.Err()
+}
+
+func (s *Subscriptions) Get(id string) (*Subscription, error) {
+	var data string
+	err := s.db.QueryRow("SELECT data FROM subscriptions WHERE id = ?", id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var sub Subscription
+	if err := json.Unmarshal([]byte(data), &sub); err != nil {
+		return nil, err
+	}
+	return &sub, nil
+}
+
+// Create assigns an ID and timestamps to the subscription and stores it.
+func (s *Subscriptions) Create(sub *Subscription) error {
+	now := time.Now().UTC().Format(time.RFC3339)
+	sub.ID = uuid.New().String()
+	sub.CreatedAt = now
+	sub.UpdatedAt = now
+	sub.HasSecret = sub.Secret != ""
+
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("INSERT INTO subscriptions (id, data, created_at) VALUES (?, ?, ?)", sub.ID, string(data), now)
+	return err
+}
+
+// Update replaces an existing subscription, keeping its ID and creation time.
+func (s *Subscriptions) Update(sub *Subscription) error {
+	existing, err := s.Get(sub.ID)
+	if err != nil {
+		return err
+	}
+	sub.CreatedAt = existing.CreatedAt
+	sub.UpdatedAt = time.Now().UTC().Format(time.RFC3339)
+	sub.HasSecret = sub.Secret != ""
+
+	data, err := json.Marshal(sub)
+	if err != nil {
+		return err
+	}
+	_, err = s.db.Exec("UPDATE subscriptions SET data = ? WHERE id = ?", string(data), sub.ID)
+	return err
+}
+
+func (s *Subscriptions) Delete(id string) error {
+	res, err := s.db.Exec("DELETE FROM subscriptions WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *Subscriptions) Close() error {
+	return s.db.Close()
+}
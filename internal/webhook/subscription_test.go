@@ -0,0 +1,133 @@
+package webhook
+
+import (
+	"path/filepath"
+	"testing"
+)
+
+func TestMatchEvent(t *testing.T) {
+	tests := []struct {
+		pattern string
+		event   string
+		want    bool
+	}{
+		{"*", "device.connected", true},
+		{"message.*", "message.receipt", true},
+		{"message.*", "message.autoreply", true},
+		{"message.*", "messages.sent", false},
+		{"message.*", "contact.opted_out", false},
+		{"device.connected", "device.connected", true},
+		{"device.connected", "device.disconnected", false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchEvent(tt.pattern, tt.event); got != tt.want {
+			t.Errorf("MatchEvent(%q, %q) = %v, want %v", tt.pattern, tt.event, got, tt.want)
+		}
+	}
+}
+
+func TestSubscription_Matches(t *testing.T) {
+	sub := Subscription{
+		Enabled: true,
+		Events:  []string{"message.*", "device.connected"},
+		Tokens:  []string{"60123456789"},
+	}
+
+	if !sub.Matches("message.receipt", "60123456789") {
+		t.Error("expected wildcard event match")
+	}
+	if !sub.Matches("device.connected", "60123456789") {
+		t.Error("expected exact event match")
+	}
+	if sub.Matches("device.logged_out", "60123456789") {
+		t.Error("expected unlisted event not to match")
+	}
+	if sub.Matches("message.receipt", "60111111111") {
+		t.Error("expected other device not to match")
+	}
+
+	sub.Enabled = false
+	if sub.Matches("message.receipt", "60123456789") {
+		t.Error("expected disabled subscription not to match")
+	}
+
+	all := Subscription{Enabled: true}
+	if !all.Matches("call.incoming", "60111111111") {
+		t.Error("expected empty filters to match everything")
+	}
+}
+
+func TestSubscription_Validate(t *testing.T) {
+	tests := []struct {
+		name    string
+		sub     Subscription
+		wantErr bool
+	}{
+		{"valid", Subscription{URL: "https://example.com/hook", Events: []string{"message.*"}, Tokens: []string{"60123456789"}}, false},
+		{"all events", Subscription{URL: "http://localhost:8080", Events: []string{"*"}}, false},
+		{"missing url", Subscription{}, true},
+		{"relative url", Subscription{URL: "/hook"}, true},
+		{"bad scheme", Subscription{URL: "ftp://example.com"}, true},
+		{"bad event", Subscription{URL: "https://example.com", Events: []string{"message*"}}, true},
+		{"empty event", Subscription{URL: "https://example.com", Events: []string{""}}, true},
+		{"bad token", Subscription{URL: "https://example.com", Tokens: []string{"abc"}}, true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.sub.Validate()
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Validate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestSubscriptions_CRUD(t *testing.T) {
+	s, err := NewSubscriptions(filepath.Join(t.TempDir(), "webhooks.db"))
+	if err != nil {
+		t.Fatalf("failed to create store: %v", err)
+	}
+	defer func() { _ = s.Close() }()
+
+	sub := Subscription{URL: "https://example.com/hook", Secret: "s3cret", Events: []string{"message.*"}, Enabled: true}
+	if err := s.Create(&sub); err != nil {
+		t.Fatalf("create failed: %v", err)
+	}
+	if sub.ID == "" || !sub.HasSecret {
+		t.Fatalf("expected ID and hasSecret to be set, got %+v", sub)
+	}
+
+	got, err := s.Get(sub.ID)
+	if err != nil || got.Secret != "s3cret" || got.Events[0] != "message.*" {
+		t.Fatalf("unexpected get result: %+v err=%v", got, err)
+	}
+	if got.Redacted().Secret != "" || !got.Redacted().HasSecret {
+		t.Fatalf("expected redacted copy to hide the secret")
+	}
+
+	got.Secret = ""
+	got.Enabled = false
+	if err := s.Update(got); err != nil {
+		t.Fatalf("update failed: %v", err)
+	}
+	if got.HasSecret || got.CreatedAt != sub.CreatedAt {
+		t.Fatalf("unexpected updated subscription: %+v", got)
+	}
+
+	list, err := s.List()
+	if err != nil || len(list) != 1 || list[0].Enabled {
+		t.Fatalf("unexpected list: %+v err=%v", list, err)
+	}
+
+	if err := s.Delete(sub.ID); err != nil {
+		t.Fatalf("delete failed: %v", err)
+	}
+	if err := s.Delete(sub.ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+	if err := s.Update(&Subscription{ID: sub.ID}); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound on update, got %v", err)
+	}
+}
@@ -2,22 +2,119 @@ package webhook
 
 import (
 	"bytes"
+	"context"
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/rand/v2"
 	"net/http"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 
+	"github.com/google/uuid"
 	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/webhooksig"
+)
+
+const (
+	// pollInterval bounds how long a scheduled retry waits past its due time
+	pollInterval = time.Second
+	// defaultConcurrency is how many due deliveries one poll attempts
+	// concurrently when Config.Concurrency is unset
+	defaultConcurrency = 16
+	// maxErrorLength truncates error messages and response bodies stored with
+	// deliveries
+	maxErrorLength = 500
+	// pruneInterval is how often expired delivery log entries are removed
+	pruneInterval = time.Hour
+	// devicePrefix marks outbox entries routed to a device's own endpoint
+	devicePrefix = "device:"
+	// legacySignatureHeader carries the bare HMAC of the body that receivers
+	// verified before webhooksig.HeaderSignature. Deprecated: it will be
+	// removed in the next release.
+	legacySignatureHeader = "X-Webhook-Signature"
 )
 
+// Ordering modes. OrderingDevice delivers each device's events to an endpoint
+// one at a time in the order they occurred; OrderingChat does so per chat,
+// with events that have no chat (such as device.connected) queued per device.
+const (
+	OrderingNone   = "none"
+	OrderingDevice = "device"
+	OrderingChat   = "chat"
+)
+
+// ErrDisabled is returned by outbox operations when no outbox is configured.
+var ErrDisabled = errors.New("webhook outbox disabled")
+
+// Config configures a Dispatcher. Secret signs requests to URL; Secrets
+// replaces it with several secrets while they are rotated. DBPath holds the
+// outbox and subscription registry; without it each event is posted once to
+// the device endpoint or URL, with no retries, and there are no subscriptions.
+// LogRetention is how long delivery attempts are kept; zero disables the log.
+// Format is the default payload format (FormatJSON if empty) and Source the
+// CloudEvents source attribute. Ordering selects ordered delivery and
+// Concurrency bounds how many requests are attempted at once. A BatchSize
+// above 1 sends up to that many events per request, waiting at most
+// BatchWindow for a batch to fill. Every event is also published to Sinks;
+// DisableHTTP leaves them as the only destination.
+type Config struct {
+	URL          string
+	Secret       string
+	Secrets      []string
+	Format       string
+	Source       string
+	Timeout      time.Duration
+	MaxAttempts  int
+	RetryBase    time.Duration
+	RetryMax     time.Duration
+	Ordering     string
+	Concurrency  int
+	BatchSize    int
+	BatchWindow  time.Duration
+	LogRetention time.Duration
+	DBPath       string
+	Sinks        []Sink
+	DisableHTTP  bool
+}
+
 type Dispatcher struct {
-	url     string
-	secret  string
-	timeout time.Duration
-	client  *http.Client
-	logger  zerolog.Logger
+	url         string
+	secrets     []string
+	format      string
+	source      string
+	maxAttempts int
+	retryBase   time.Duration
+	retryMax    time.Duration
+	ordering    string
+	concurrency int
+	batchSize   int
+	batchWindow time.Duration
+	retention   time.Duration
+	outbox      *Outbox
+	registry    *Subscriptions
+	sinks       []Sink
+	http        bool
+	client      *http.Client
+	logger      zerolog.Logger
+
+	// subscriptions caches the registry for matching on every Send;
+	// devices holds per-device endpoints that replace the global URL
+	mu            sync.RWMutex
+	subscriptions []Subscription
+	devices       map[string]Subscription
+
+	wake chan struct{}
+	stop chan struct{}
+	wg   sync.WaitGroup
+	once sync.Once
 }
 
 type Payload struct {
@@ -27,27 +124,120 @@ type Payload struct {
 	Timestamp string      `json:"timestamp"`
 }
 
-func NewDispatcher(url, secret string, timeoutMs int, logger zerolog.Logger) *Dispatcher {
-	return &Dispatcher{
-		url:     url,
-		secret:  secret,
-		timeout: time.Duration(timeoutMs) * time.Millisecond,
+func NewDispatcher(cfg Config, logger zerolog.Logger) (*Dispatcher, error) {
+	if cfg.MaxAttempts < 1 {
+		cfg.MaxAttempts = 1
+	}
+	if cfg.Format == "" {
+		cfg.Format = FormatJSON
+	}
+	if cfg.Source == "" {
+		cfg.Source = DefaultSource
+	}
+	if cfg.Concurrency < 1 {
+		cfg.Concurrency = defaultConcurrency
+	}
+	d := &Dispatcher{
+		url:         cfg.URL,
+		secrets:     cfg.Secrets,
+		format:      cfg.Format,
+		source:      cfg.Source,
+		maxAttempts: cfg.MaxAttempts,
+		retryBase:   cfg.RetryBase,
+		retryMax:    cfg.RetryMax,
+		ordering:    cfg.Ordering,
+		concurrency: cfg.Concurrency,
+		batchSize:   cfg.BatchSize,
+		batchWindow: cfg.BatchWindow,
+		retention:   cfg.LogRetention,
+		sinks:       cfg.Sinks,
+		http:        !cfg.DisableHTTP,
 		client: &http.Client{
-			Timeout: time.Duration(timeoutMs) * time.Millisecond,
+			Timeout: cfg.Timeout,
 		},
 		logger: logger.With().Str("component", "webhook").Logger(),
+		wake:   make(chan struct{}, 1),
+		stop:   make(chan struct{}),
+
+		devices: make(map[string]Subscription),
+	}
+	if len(d.secrets) == 0 && cfg.Secret != "" {
+		d.secrets = []string{cfg.Secret}
+	}
+
+	if cfg.DBPath != "" {
+		outbox, err := NewOutbox(cfg.DBPath)
+		if err != nil {
+			return nil, fmt.Errorf("failed to open webhook outbox: %w", err)
+		}
+		d.outbox = outbox
+
+		registry, err := NewSubscriptions(cfg.DBPath)
+		if err != nil {
+			_ = outbox.Close()
+			return nil, fmt.Errorf("failed to open webhook subscriptions: %w", err)
+		}
+		d.registry = registry
+
+		if err := d.reloadSubscriptions(); err != nil {
+			_ = d.Close()
+			return nil, fmt.Errorf("failed to load webhook subscriptions: %w", err)
+		}
+
+		if d.batchSize <= 1 {
+			if err := outbox.ClearBatches(); err != nil {
+				_ = d.Close()
+				return nil, fmt.Errorf("failed to clear webhook batches: %w", err)
+			}
+		}
+	} else if d.http && d.url != "" {
+		d.logger.Warn().Msg("webhook outbox disabled, webhooks are sent once without retries")
 	}
+
+	return d, nil
 }
 
-func (d *Dispatcher) Send(event, token string, data interface{}) {
-	if d.url == "" {
+// Start launches the delivery worker. Deliveries left in the outbox by a
+// previous run are picked up immediately.
+func (d *Dispatcher) Start() {
+	if d.outbox == nil {
 		return
 	}
+	d.wg.Add(1)
+	go d.run()
+}
 
-	go d.dispatch(event, token, data)
+// Close stops the worker, waits for in-flight requests and closes the outbox.
+// Pending deliveries stay in the outbox for the next start.
+func (d *Dispatcher) Close() error {
+	d.once.Do(func() { close(d.stop) })
+	d.wg.Wait()
+	for _, sink := range d.sinks {
+		if err := sink.Close(); err != nil {
+			d.logger.Error().Err(err).Str("sink", sink.Name()).Msg("failed to close event sink")
+		}
+	}
+	if d.registry != nil {
+		_ = d.registry.Close()
+	}
+	if d.outbox != nil {
+		return d.outbox.Close()
+	}
+	return nil
 }
 
-func (d *Dispatcher) dispatch(event, token string, data interface{}) {
+// Send publishes the event to every sink and queues it for the device's own
+// endpoint (or the global WEBHOOK_URL if it has none) and every enabled
+// subscription whose filters match. Without an outbox, it is posted at once.
+func (d *Dispatcher) Send(event, token string, data interface{}) {
+	var targets []Subscription
+	if d.http {
+		targets = d.targets(event, token)
+	}
+	if len(targets) == 0 && len(d.sinks) == 0 {
+		return
+	}
+
 	payload := Payload{
 		Event:     event,
 		Token:     token,
@@ -61,32 +251,547 @@ func (d *Dispatcher) dispatch(event, token string, data interface{}) {
 		return
 	}
 
-	req, err := http.NewRequest("POST", d.url, bytes.NewReader(body))
+	if len(d.sinks) > 0 {
+		id := uuid.New().String()
+		for _, sink := range d.sinks {
+			if err := sink.Publish(event, token, id, body); err != nil {
+				d.logger.Error().Err(err).Str("event", event).Str("sink", sink.Name()).Msg("failed to publish event")
+			}
+		}
+	}
+	if len(targets) == 0 {
+		return
+	}
+	if d.outbox == nil {
+		for _, t := range targets {
+			go d.sendNow(event, token, t, body)
+		}
+		return
+	}
+
+	chat := ""
+	if d.ordering == OrderingChat {
+		chat = chatOf(data)
+	}
+	for _, t := range targets {
+		if _, err := d.outbox.Enqueue(event, token, t.ID, t.URL, d.orderKey(t.ID, token, chat), body); err != nil {
+			d.logger.Error().Err(err).Str("event", event).Str("subscription", t.ID).Msg("failed to enqueue webhook")
+		}
+	}
+	d.notify()
+}
+
+// sendNow posts an event once when there is no outbox to queue it in. A
+// failure is only logged, since nothing is left to retry.
+func (d *Dispatcher) sendNow(event, token string, target Subscription, body []byte) {
+	if target.Format == "" {
+		target.Format = d.format
+	}
+	delivery := Delivery{
+		WebhookID:      uuid.New().String(),
+		Event:          event,
+		Token:          token,
+		SubscriptionID: target.ID,
+		URL:            target.URL,
+		Payload:        body,
+	}
+	if _, _, err := d.post(target, []Delivery{delivery}); err != nil {
+		d.logger.Error().Err(err).Str("event", event).Str("url", target.URL).Msg("webhook request failed, not retried without an outbox")
+	}
+}
+
+// orderKey returns the queue a delivery joins under the ordering mode: one
+// per endpoint and device, or per endpoint, device and chat. The global
+// endpoint has an empty subscription ID.
+func (d *Dispatcher) orderKey(subscriptionID, token, chat string) string {
+	switch d.ordering {
+	case OrderingDevice:
+		return subscriptionID + "|" + token
+	case OrderingChat:
+		return subscriptionID + "|" + token + "|" + chat
+	}
+	return ""
+}
+
+// chatOf returns the contact or group an event belongs to, normalized to the
+// user part of its JID, or "" for device-level events.
+func chatOf(data interface{}) string {
+	var get func(string) string
+	switch v := data.(type) {
+	case map[string]interface{}:
+		get = func(k string) string { s, _ := v[k].(string); return s }
+	case map[string]string:
+		get = func(k string) string { return v[k] }
+	default:
+		return ""
+	}
+	for _, k := range []string{"chat", "from", "to", "phone"} {
+		if s := get(k); s != "" {
+			s, _, _ = strings.Cut(s, "@")
+			s, _, _ = strings.Cut(s, ":")
+			return s
+		}
+	}
+	return ""
+}
+
+// targets returns the endpoints for an event: the device endpoint or global
+// URL (empty ID) followed by matching subscriptions.
+func (d *Dispatcher) targets(event, token string) []Subscription {
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+
+	var targets []Subscription
+	if device, ok := d.devices[token]; ok {
+		targets = append(targets, device)
+	} else if d.url != "" {
+		targets = append(targets, Subscription{URL: d.url, secrets: d.secrets})
+	}
+
+	for _, sub := range d.subscriptions {
+		if sub.Matches(event, token) {
+			targets = append(targets, sub)
+		}
+	}
+	return targets
+}
+
+// endpoint resolves where a queued delivery goes and in which format. Device
+// and subscription deliveries use the current URL, secret and format, so
+// fixing a URL also redirects pending retries. It reports false if the
+// endpoint has been removed.
+func (d *Dispatcher) endpoint(delivery Delivery) (Subscription, bool) {
+	target, ok := d.lookup(delivery)
+	if target.Format == "" {
+		target.Format = d.format
+	}
+	return target, ok
+}
+
+func (d *Dispatcher) lookup(delivery Delivery) (Subscription, bool) {
+	if delivery.SubscriptionID == "" {
+		return Subscription{URL: delivery.URL, secrets: d.secrets}, true
+	}
+
+	d.mu.RLock()
+	defer d.mu.RUnlock()
+	if strings.HasPrefix(delivery.SubscriptionID, devicePrefix) {
+		device, ok := d.devices[delivery.Token]
+		return device, ok
+	}
+	for _, sub := range d.subscriptions {
+		if sub.ID == delivery.SubscriptionID {
+			return sub, true
+		}
+	}
+	return Subscription{}, false
+}
+
+// DeadLetters lists deliveries that were given up on.
+func (d *Dispatcher) DeadLetters(limit, offset int) ([]DeadLetter, int, error) {
+	if d.outbox == nil {
+		return nil, 0, ErrDisabled
+	}
+	return d.outbox.DeadLetters(limit, offset)
+}
+
+// Replay re-queues a dead letter for delivery.
+func (d *Dispatcher) Replay(id int64) error {
+	if d.outbox == nil {
+		return ErrDisabled
+	}
+	if _, err := d.outbox.Replay(id); err != nil {
+		return err
+	}
+	d.notify()
+	return nil
+}
+
+// Attempts lists logged delivery attempts.
+func (d *Dispatcher) Attempts(filter AttemptFilter) ([]DeliveryAttempt, int, error) {
+	if d.outbox == nil {
+		return nil, 0, ErrDisabled
+	}
+	return d.outbox.Attempts(filter)
+}
+
+// Redeliver queues a logged attempt's payload for delivery again, whether or
+// not it succeeded.
+func (d *Dispatcher) Redeliver(id int64) error {
+	if d.outbox == nil {
+		return ErrDisabled
+	}
+	if _, err := d.outbox.Redeliver(id); err != nil {
+		return err
+	}
+	d.notify()
+	return nil
+}
+
+// Subscriptions lists registered webhook subscriptions.
+func (d *Dispatcher) Subscriptions() ([]Subscription, error) {
+	if d.registry == nil {
+		return nil, ErrDisabled
+	}
+	return d.registry.List()
+}
+
+func (d *Dispatcher) Subscription(id string) (*Subscription, error) {
+	if d.registry == nil {
+		return nil, ErrDisabled
+	}
+	return d.registry.Get(id)
+}
+
+func (d *Dispatcher) CreateSubscription(sub *Subscription) error {
+	if d.registry == nil {
+		return ErrDisabled
+	}
+	if err := d.registry.Create(sub); err != nil {
+		return err
+	}
+	return d.reloadSubscriptions()
+}
+
+func (d *Dispatcher) UpdateSubscription(sub *Subscription) error {
+	if d.registry == nil {
+		return ErrDisabled
+	}
+	if err := d.registry.Update(sub); err != nil {
+		return err
+	}
+	return d.reloadSubscriptions()
+}
+
+// DeleteSubscription removes the subscription. Its pending deliveries are
+// dropped when next attempted.
+func (d *Dispatcher) DeleteSubscription(id string) error {
+	if d.registry == nil {
+		return ErrDisabled
+	}
+	if err := d.registry.Delete(id); err != nil {
+		return err
+	}
+	return d.reloadSubscriptions()
+}
+
+// SetDeviceEndpoint routes the device's events to url instead of the global
+// WEBHOOK_URL, signed with secret. An empty url restores the global endpoint.
+func (d *Dispatcher) SetDeviceEndpoint(token, url, secret string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	if url == "" {
+		delete(d.devices, token)
+		return
+	}
+	d.devices[token] = Subscription{ID: devicePrefix + token, URL: url, Secret: secret}
+}
+
+func (d *Dispatcher) reloadSubscriptions() error {
+	subs, err := d.registry.List()
+	if err != nil {
+		return err
+	}
+	d.mu.Lock()
+	d.subscriptions = subs
+	d.mu.Unlock()
+	return nil
+}
+
+func (d *Dispatcher) notify() {
+	select {
+	case d.wake <- struct{}{}:
+	default:
+	}
+}
+
+func (d *Dispatcher) run() {
+	defer d.wg.Done()
+
+	interval := pollInterval
+	if d.batchSize > 1 && d.batchWindow > 0 && d.batchWindow < interval {
+		interval = d.batchWindow
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	prune := time.NewTicker(pruneInterval)
+	defer prune.Stop()
+
+	d.prune()
+	for {
+		d.flush()
+
+		select {
+		case <-d.stop:
+			return
+		case <-d.wake:
+		case <-ticker.C:
+		case <-prune.C:
+			d.prune()
+		}
+	}
+}
+
+// prune removes delivery log entries older than the retention period.
+func (d *Dispatcher) prune() {
+	if d.retention <= 0 {
+		return
+	}
+	n, err := d.outbox.PruneAttempts(time.Now().Add(-d.retention))
+	if err != nil {
+		d.logger.Error().Err(err).Msg("failed to prune webhook delivery log")
+		return
+	}
+	if n > 0 {
+		d.logger.Debug().Int64("removed", n).Msg("pruned webhook delivery log")
+	}
+}
+
+// flush attempts every due delivery, or every due batch when batching is
+// enabled, up to the concurrency limit at a time until none are due.
+func (d *Dispatcher) flush() {
+	for {
+		select {
+		case <-d.stop:
+			return
+		default:
+		}
+
+		due, err := d.due()
+		if err != nil {
+			d.logger.Error().Err(err).Msg("failed to read webhook outbox")
+			return
+		}
+		if len(due) == 0 {
+			return
+		}
+
+		var wg sync.WaitGroup
+		for _, deliveries := range due {
+			wg.Add(1)
+			go func(deliveries []Delivery) {
+				defer wg.Done()
+				d.attempt(deliveries)
+			}(deliveries)
+		}
+		wg.Wait()
+	}
+}
+
+// due returns the next requests to attempt: single deliveries, or batches
+// formed from waiting deliveries when batching is enabled.
+func (d *Dispatcher) due() ([][]Delivery, error) {
+	now := time.Now()
+	if d.batchSize > 1 {
+		if err := d.outbox.FormBatches(now, d.batchSize, d.batchWindow, d.ordering != OrderingNone); err != nil {
+			return nil, err
+		}
+		return d.outbox.DueBatches(now, d.concurrency)
+	}
+
+	deliveries, err := d.outbox.Due(now, d.concurrency)
+	if err != nil {
+		return nil, err
+	}
+	due := make([][]Delivery, len(deliveries))
+	for i, delivery := range deliveries {
+		due[i] = []Delivery{delivery}
+	}
+	return due, nil
+}
+
+// attempt makes one delivery attempt for a delivery or a batch sharing an
+// endpoint and records the outcome in the outbox. A batch succeeds or fails
+// as a whole.
+func (d *Dispatcher) attempt(deliveries []Delivery) {
+	first := deliveries[0]
+	logger := d.logger.With().Int64("id", first.ID).Str("event", first.Event).Int("count", len(deliveries)).Logger()
+	attempts := first.Attempts + 1
+
+	target, ok := d.endpoint(first)
+	if !ok {
+		for _, delivery := range deliveries {
+			if err := d.outbox.Complete(delivery.ID); err != nil {
+				logger.Error().Err(err).Msg("failed to remove webhook for deleted subscription")
+			}
+		}
+		logger.Info().Str("subscription", first.SubscriptionID).Msg("webhook endpoint removed, dropping webhook")
+		return
+	}
+
+	result, retryable, err := d.post(target, deliveries)
+
+	select {
+	case <-d.stop:
+		if err != nil {
+			// Interrupted by shutdown; leave the entry due for the next start
+			return
+		}
+	default:
+	}
+
+	var lastErr string
 	if err != nil {
-		d.logger.Error().Err(err).Str("event", event).Msg("failed to create webhook request")
+		lastErr = truncate(err.Error())
+	}
+	for _, delivery := range deliveries {
+		d.record(delivery, target.URL, attempts, result, lastErr)
+	}
+
+	if err == nil {
+		for _, delivery := range deliveries {
+			if err := d.outbox.Complete(delivery.ID); err != nil {
+				logger.Error().Err(err).Msg("failed to remove delivered webhook")
+			}
+		}
+		logger.Debug().Int("attempts", attempts).Msg("webhook delivered")
 		return
 	}
 
-	req.Header.Set("Content-Type", "application/json")
+	if !retryable || attempts >= d.maxAttempts {
+		for _, delivery := range deliveries {
+			if err := d.outbox.Kill(delivery.ID, attempts, lastErr); err != nil {
+				logger.Error().Err(err).Msg("failed to dead-letter webhook")
+				return
+			}
+		}
+		logger.Warn().Int("attempts", attempts).Str("error", lastErr).Msg("webhook moved to dead letters")
+		return
+	}
+
+	next := time.Now().Add(backoff(d.retryBase, d.retryMax, attempts))
+	for _, delivery := range deliveries {
+		if err := d.outbox.Retry(delivery.ID, attempts, next, lastErr); err != nil {
+			logger.Error().Err(err).Msg("failed to schedule webhook retry")
+			return
+		}
+	}
+	logger.Warn().Int("attempts", attempts).Time("nextAttempt", next).Str("error", lastErr).Msg("webhook failed, will retry")
+}
+
+// attemptResult is what the receiver returned for one attempt.
+type attemptResult struct {
+	statusCode int
+	latency    time.Duration
+	response   string
+}
+
+// record adds the attempt to the delivery log when it is enabled.
+func (d *Dispatcher) record(delivery Delivery, url string, attempt int, result attemptResult, lastErr string) {
+	if d.retention <= 0 {
+		return
+	}
+	err := d.outbox.RecordAttempt(&DeliveryAttempt{
+		WebhookID:      delivery.WebhookID,
+		Event:          delivery.Event,
+		Token:          delivery.Token,
+		SubscriptionID: delivery.SubscriptionID,
+		URL:            url,
+		Payload:        delivery.Payload,
+		Attempt:        attempt,
+		Success:        lastErr == "",
+		StatusCode:     result.statusCode,
+		LatencyMs:      result.latency.Milliseconds(),
+		Response:       result.response,
+		Error:          lastErr,
+	})
+	if err != nil {
+		d.logger.Error().Err(err).Int64("id", delivery.ID).Msg("failed to log webhook attempt")
+	}
+}
+
+// post sends the deliveries and reports whether a failure is worth retrying:
+// network errors, 429 and 5xx are; other 4xx responses are not. A batch is
+// sent as a JSON array identified by its batch ID. Each of the target's
+// secrets adds a signature over the body as sent in the target's format, and
+// the first also signs the deprecated legacy header.
+func (d *Dispatcher) post(target Subscription, deliveries []Delivery) (result attemptResult, retryable bool, err error) {
+	id := deliveries[0].WebhookID
+	var body []byte
+	var header http.Header
+	if batchID := deliveries[0].BatchID; batchID != "" {
+		id = batchID
+		body, header, err = encodeBatch(target.Format, d.source, deliveries)
+	} else {
+		body, header, err = encode(target.Format, d.source, deliveries[0])
+	}
+	if err != nil {
+		return result, false, err
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go func() {
+		select {
+		case <-d.stop:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	req, err := http.NewRequestWithContext(ctx, "POST", target.URL, bytes.NewReader(body))
+	if err != nil {
+		return result, false, err
+	}
+
+	timestamp := time.Now().Unix()
+	req.Header = header
 	req.Header.Set("User-Agent", "wa-gateway-go/1.0")
+	req.Header.Set(webhooksig.HeaderID, id)
+	req.Header.Set(webhooksig.HeaderTimestamp, strconv.FormatInt(timestamp, 10))
 
-	if d.secret != "" {
-		mac := hmac.New(sha256.New, []byte(d.secret))
-		mac.Write(body)
-		sig := hex.EncodeToString(mac.Sum(nil))
-		req.Header.Set("X-Webhook-Signature", sig)
+	if secrets := target.signingSecrets(); len(secrets) > 0 {
+		req.Header.Set(webhooksig.HeaderSignature, webhooksig.SignatureHeader(secrets, timestamp, body))
+		req.Header.Set(legacySignatureHeader, legacySignature(secrets[0], body))
 	}
 
+	start := time.Now()
 	resp, err := d.client.Do(req)
 	if err != nil {
-		d.logger.Error().Err(err).Str("event", event).Msg("webhook request failed")
-		return
+		result.latency = time.Since(start)
+		return result, true, err
 	}
 	defer func() { _ = resp.Body.Close() }()
 
-	if resp.StatusCode >= 400 {
-		d.logger.Warn().Int("status", resp.StatusCode).Str("event", event).Msg("webhook returned error status")
-	} else {
-		d.logger.Debug().Str("event", event).Msg("webhook delivered")
+	snippet, _ := io.ReadAll(io.LimitReader(resp.Body, maxErrorLength))
+	result.latency = time.Since(start)
+	result.statusCode = resp.StatusCode
+	result.response = strings.ToValidUTF8(string(snippet), "")
+
+	if resp.StatusCode < 400 {
+		return result, false, nil
+	}
+	retryable = resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+	return result, retryable, fmt.Errorf("webhook returned status %d", resp.StatusCode)
+}
+
+// legacySignature returns the hex HMAC-SHA256 of body, without a timestamp.
+func legacySignature(secret string, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// backoff returns the delay before the given retry: base doubled per failed
+// attempt, capped at max, with the upper half randomized to spread retries.
+func backoff(base, max time.Duration, attempts int) time.Duration {
+	if base <= 0 {
+		return 0
+	}
+	delay := base
+	for i := 1; i < attempts && delay < max; i++ {
+		delay *= 2
+	}
+	if max > 0 && delay > max {
+		delay = max
+	}
+	half := delay / 2
+	return half + rand.N(delay-half+1)
+}
+
+func truncate(s string) string {
+	if len(s) > maxErrorLength {
+		return s[:maxErrorLength]
 	}
+	return s
 }
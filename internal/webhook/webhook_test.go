@@ -0,0 +1,645 @@
+package webhook
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/webhooksig"
+)
+
+func newTestDispatcher(t *testing.T, url string, maxAttempts int) *Dispatcher {
+	t.Helper()
+	d, err := NewDispatcher(Config{
+		URL:          url,
+		Secret:       "secret",
+		Timeout:      time.Second,
+		MaxAttempts:  maxAttempts,
+		RetryBase:    10 * time.Millisecond,
+		RetryMax:     20 * time.Millisecond,
+		LogRetention: time.Hour,
+		DBPath:       filepath.Join(t.TempDir(), "webhooks.db"),
+	}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	t.Cleanup(func() { _ = d.Close() })
+	return d
+}
+
+func waitFor(t *testing.T, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for condition")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestBackoff(t *testing.T) {
+	base, max := time.Second, 10*time.Second
+
+	tests := []struct {
+		attempts int
+		want     time.Duration
+	}{
+		{1, time.Second},
+		{2, 2 * time.Second},
+		{3, 4 * time.Second},
+		{4, 8 * time.Second},
+		{5, 10 * time.Second},
+		{50, 10 * time.Second},
+	}
+
+	for _, tt := range tests {
+		for i := 0; i < 20; i++ {
+			got := backoff(base, max, tt.attempts)
+			if got < tt.want/2 || got > tt.want {
+				t.Fatalf("backoff(attempts=%d) = %v, want within [%v, %v]", tt.attempts, got, tt.want/2, tt.want)
+			}
+		}
+	}
+}
+
+func TestDispatcher_RetriesUntilDelivered(t *testing.T) {
+	var calls atomic.Int32
+	var delivered atomic.Value
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		body, _ := io.ReadAll(r.Body)
+		delivered.Store(body)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(t, srv.URL, 5)
+	d.Start()
+	d.Send("device.connected", "60123456789", nil)
+
+	waitFor(t, func() bool { return delivered.Load() != nil })
+
+	var payload Payload
+	if err := json.Unmarshal(delivered.Load().([]byte), &payload); err != nil {
+		t.Fatalf("invalid payload: %v", err)
+	}
+	if payload.Event != "device.connected" || payload.Token != "60123456789" {
+		t.Fatalf("unexpected payload: %+v", payload)
+	}
+
+	waitFor(t, func() bool {
+		n, _ := d.outbox.Pending()
+		return n == 0
+	})
+	if _, total, _ := d.DeadLetters(10, 0); total != 0 {
+		t.Fatalf("expected no dead letters, got %d", total)
+	}
+}
+
+func TestDispatcher_DeadLettersAndReplay(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(t, srv.URL, 3)
+	d.Start()
+	d.Send("message.receipt", "60123456789", map[string]string{"type": "read"})
+
+	var letters []DeadLetter
+	waitFor(t, func() bool {
+		letters, _, _ = d.DeadLetters(10, 0)
+		return len(letters) == 1
+	})
+	if calls.Load() != 3 || letters[0].Attempts != 3 {
+		t.Fatalf("expected 3 attempts before dead-lettering, got calls=%d attempts=%d", calls.Load(), letters[0].Attempts)
+	}
+	if letters[0].LastError != "webhook returned status 500" {
+		t.Fatalf("unexpected last error: %q", letters[0].LastError)
+	}
+
+	status.Store(http.StatusOK)
+	if err := d.Replay(letters[0].ID); err != nil {
+		t.Fatalf("replay failed: %v", err)
+	}
+	waitFor(t, func() bool { return calls.Load() == 4 })
+	waitFor(t, func() bool {
+		n, _ := d.outbox.Pending()
+		return n == 0
+	})
+	if err := d.Replay(letters[0].ID); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDispatcher_ClientErrorIsNotRetried(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusBadRequest)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(t, srv.URL, 5)
+	d.Start()
+	d.Send("device.connected", "60123456789", nil)
+
+	waitFor(t, func() bool {
+		_, total, _ := d.DeadLetters(10, 0)
+		return total == 1
+	})
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single attempt for 400, got %d", calls.Load())
+	}
+}
+
+func TestDispatcher_DeliveryLogAndRedeliver(t *testing.T) {
+	var calls atomic.Int32
+	var ids sync.Map
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		ids.Store(calls.Add(1), r.Header.Get(webhooksig.HeaderID))
+		if calls.Load() == 1 {
+			w.WriteHeader(http.StatusBadGateway)
+			_, _ = w.Write([]byte("upstream down"))
+			return
+		}
+		_, _ = w.Write([]byte(`{"ok":true}`))
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(t, srv.URL, 3)
+	d.Start()
+	d.Send("message.receipt", "60123456789", nil)
+
+	var attempts []DeliveryAttempt
+	waitFor(t, func() bool {
+		attempts, _, _ = d.Attempts(AttemptFilter{Limit: 10})
+		return len(attempts) == 2
+	})
+
+	failed, delivered := attempts[1], attempts[0]
+	if failed.Success || failed.StatusCode != 502 || failed.Response != "upstream down" || failed.Error != "webhook returned status 502" || failed.Attempt != 1 {
+		t.Fatalf("unexpected failed attempt: %+v", failed)
+	}
+	if !delivered.Success || delivered.StatusCode != 200 || delivered.Response != `{"ok":true}` || delivered.Attempt != 2 || delivered.URL != srv.URL {
+		t.Fatalf("unexpected delivered attempt: %+v", delivered)
+	}
+
+	if _, total, _ := d.Attempts(AttemptFilter{Status: "failed", Limit: 10}); total != 1 {
+		t.Fatalf("expected 1 failed attempt, got %d", total)
+	}
+	if _, total, _ := d.Attempts(AttemptFilter{Event: "device.*", Limit: 10}); total != 0 {
+		t.Fatalf("expected no attempts for device.*, got %d", total)
+	}
+
+	if err := d.Redeliver(delivered.ID); err != nil {
+		t.Fatalf("redeliver failed: %v", err)
+	}
+	waitFor(t, func() bool { return calls.Load() == 3 })
+	first, _ := ids.Load(int32(1))
+	third, _ := ids.Load(int32(3))
+	if first != third {
+		t.Fatalf("expected redelivery to keep webhook ID %v, got %v", first, third)
+	}
+	if err := d.Redeliver(9999); err != ErrNotFound {
+		t.Fatalf("expected ErrNotFound, got %v", err)
+	}
+}
+
+func TestDispatcher_OrderedDelivery(t *testing.T) {
+	var failed atomic.Bool
+	received := make(chan string, 10)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Payload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		if p.Event == "device.connected" && failed.CompareAndSwap(false, true) {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		received <- p.Event
+	}))
+	defer srv.Close()
+
+	d, err := NewDispatcher(Config{
+		URL:         srv.URL,
+		Timeout:     time.Second,
+		MaxAttempts: 5,
+		RetryBase:   50 * time.Millisecond,
+		RetryMax:    50 * time.Millisecond,
+		Ordering:    OrderingDevice,
+		DBPath:      filepath.Join(t.TempDir(), "webhooks.db"),
+	}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+
+	events := []string{"device.connected", "message.receipt", "message.autoreply", "contacts.new"}
+	for _, e := range events {
+		d.Send(e, "60123456789", nil)
+	}
+	d.Start()
+
+	// device.connected fails once; later events must wait for its retry
+	for i, want := range events {
+		select {
+		case got := <-received:
+			if got != want {
+				t.Fatalf("delivery %d: got %s, want %s", i, got, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out waiting for %s", want)
+		}
+	}
+}
+
+func TestChatOf(t *testing.T) {
+	tests := []struct {
+		data interface{}
+		want string
+	}{
+		{nil, ""},
+		{map[string]interface{}{"chat": "120363@g.us", "from": "60198765432@s.whatsapp.net"}, "120363"},
+		{map[string]interface{}{"from": "60198765432:12@s.whatsapp.net"}, "60198765432"},
+		{map[string]string{"phone": "60198765432"}, "60198765432"},
+		{map[string]interface{}{"to": "60198765432", "type": "text"}, "60198765432"},
+		{[]string{"60198765432"}, ""},
+	}
+	for _, tt := range tests {
+		if got := chatOf(tt.data); got != tt.want {
+			t.Errorf("chatOf(%v) = %q, want %q", tt.data, got, tt.want)
+		}
+	}
+}
+
+func TestDispatcher_PersistsAcrossRestart(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "webhooks.db")
+	logger := zerolog.New(io.Discard)
+
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	cfg := Config{URL: srv.URL, Timeout: time.Second, MaxAttempts: 3, DBPath: dbPath}
+
+	// Not started: the event only reaches the outbox
+	d, err := NewDispatcher(cfg, logger)
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	d.Send("device.connected", "60123456789", nil)
+	if err := d.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+	if calls.Load() != 0 {
+		t.Fatalf("expected no delivery before start, got %d", calls.Load())
+	}
+
+	d, err = NewDispatcher(cfg, logger)
+	if err != nil {
+		t.Fatalf("failed to reopen dispatcher: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+	d.Start()
+
+	waitFor(t, func() bool { return calls.Load() == 1 })
+}
+
+func TestDispatcher_Disabled(t *testing.T) {
+	d, err := NewDispatcher(Config{}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	d.Start()
+	d.Send("device.connected", "60123456789", nil)
+
+	if _, _, err := d.DeadLetters(10, 0); err != ErrDisabled {
+		t.Fatalf("expected ErrDisabled, got %v", err)
+	}
+	if err := d.Replay(1); err != ErrDisabled {
+		t.Fatalf("expected ErrDisabled, got %v", err)
+	}
+	if err := d.Close(); err != nil {
+		t.Fatalf("close failed: %v", err)
+	}
+}
+
+func TestDispatcher_DeliversWithoutOutbox(t *testing.T) {
+	received := make(chan Payload, 1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var p Payload
+		_ = json.NewDecoder(r.Body).Decode(&p)
+		received <- p
+	}))
+	defer srv.Close()
+
+	d, err := NewDispatcher(Config{URL: srv.URL, Secret: "secret", Timeout: time.Second}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+	d.Start()
+	d.Send("device.connected", "60123456789", nil)
+
+	select {
+	case p := <-received:
+		if p.Event != "device.connected" || p.Token != "60123456789" {
+			t.Fatalf("unexpected payload: %+v", p)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for direct delivery")
+	}
+}
+
+func TestDispatcher_SubscriptionFanOut(t *testing.T) {
+	type hit struct {
+		event     string
+		signature string
+	}
+	received := make(chan hit, 10)
+	newEndpoint := func() *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var p Payload
+			_ = json.NewDecoder(r.Body).Decode(&p)
+			received <- hit{event: r.URL.Path + " " + p.Event, signature: r.Header.Get(webhooksig.HeaderSignature)}
+		}))
+	}
+	messages, devices := newEndpoint(), newEndpoint()
+	defer messages.Close()
+	defer devices.Close()
+
+	d := newTestDispatcher(t, "", 3)
+	subs := []Subscription{
+		{URL: messages.URL + "/messages", Secret: "msg-secret", Events: []string{"message.*"}, Enabled: true},
+		{URL: devices.URL + "/devices", Events: []string{"device.*"}, Tokens: []string{"60123456789"}, Enabled: true},
+		{URL: devices.URL + "/disabled", Enabled: false},
+	}
+	for i := range subs {
+		if err := d.CreateSubscription(&subs[i]); err != nil {
+			t.Fatalf("create subscription failed: %v", err)
+		}
+	}
+	d.Start()
+
+	d.Send("message.receipt", "60111111111", nil)
+	d.Send("device.connected", "60111111111", nil) // token filtered out
+	d.Send("device.connected", "60123456789", nil)
+
+	got := map[string]string{}
+	for i := 0; i < 2; i++ {
+		select {
+		case h := <-received:
+			got[h.event] = h.signature
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out, got %v", got)
+		}
+	}
+	select {
+	case h := <-received:
+		t.Fatalf("unexpected extra delivery: %+v", h)
+	case <-time.After(200 * time.Millisecond):
+	}
+
+	if sig, ok := got["/messages message.receipt"]; !ok || sig == "" {
+		t.Errorf("expected signed message.receipt delivery, got %v", got)
+	}
+	if sig, ok := got["/devices device.connected"]; !ok || sig != "" {
+		t.Errorf("expected unsigned device.connected delivery, got %v", got)
+	}
+}
+
+func TestDispatcher_CloudEventsSubscription(t *testing.T) {
+	type hit struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan hit, 2)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- hit{header: r.Header, body: body}
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(t, "", 3)
+	sub := Subscription{URL: srv.URL, Secret: "ce-secret", Format: FormatCloudEvents, Enabled: true}
+	if err := d.CreateSubscription(&sub); err != nil {
+		t.Fatalf("create subscription failed: %v", err)
+	}
+	d.Start()
+	d.Send("message.received", "60123456789", map[string]string{"id": "ABC"})
+
+	var h hit
+	select {
+	case h = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+
+	var ce CloudEvent
+	if err := json.Unmarshal(h.body, &ce); err != nil {
+		t.Fatalf("invalid CloudEvent %s: %v", h.body, err)
+	}
+	if ce.Type != "message.received" || ce.Subject != "60123456789" || ce.ID != h.header.Get(webhooksig.HeaderID) || ce.Source != DefaultSource {
+		t.Errorf("unexpected CloudEvent: %+v", ce)
+	}
+	if h.header.Get("Content-Type") != "application/cloudevents+json" {
+		t.Errorf("unexpected content type %q", h.header.Get("Content-Type"))
+	}
+	if err := webhooksig.Verify(h.header, h.body, []string{"ce-secret"}, 0); err != nil {
+		t.Errorf("signature should cover the CloudEvent body: %v", err)
+	}
+}
+
+func TestDispatcher_DeletedSubscriptionDropsPending(t *testing.T) {
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer srv.Close()
+
+	d := newTestDispatcher(t, "", 3)
+	sub := Subscription{URL: srv.URL, Enabled: true}
+	if err := d.CreateSubscription(&sub); err != nil {
+		t.Fatalf("create subscription failed: %v", err)
+	}
+
+	d.Send("device.connected", "60123456789", nil)
+	if err := d.DeleteSubscription(sub.ID); err != nil {
+		t.Fatalf("delete subscription failed: %v", err)
+	}
+	d.Start()
+
+	waitFor(t, func() bool {
+		n, _ := d.outbox.Pending()
+		return n == 0
+	})
+	if calls.Load() != 0 {
+		t.Fatalf("expected no delivery for deleted subscription, got %d", calls.Load())
+	}
+}
+
+func TestDispatcher_DeviceEndpoint(t *testing.T) {
+	type hit struct {
+		target string
+		signed bool
+	}
+	received := make(chan hit, 10)
+	newEndpoint := func(name string) *httptest.Server {
+		return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			var p Payload
+			_ = json.NewDecoder(r.Body).Decode(&p)
+			received <- hit{target: name + " " + p.Token, signed: r.Header.Get(webhooksig.HeaderSignature) != ""}
+		}))
+	}
+	global, client := newEndpoint("global"), newEndpoint("client")
+	defer global.Close()
+	defer client.Close()
+
+	d := newTestDispatcher(t, global.URL, 3)
+	d.SetDeviceEndpoint("60123456789", client.URL, "")
+	d.Start()
+
+	d.Send("device.connected", "60123456789", nil)
+	d.Send("device.connected", "60111111111", nil)
+
+	got := map[string]bool{}
+	for i := 0; i < 2; i++ {
+		select {
+		case h := <-received:
+			got[h.target] = h.signed
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out, got %v", got)
+		}
+	}
+	// The device endpoint has no secret, so its requests are unsigned
+	if signed, ok := got["client 60123456789"]; !ok || signed {
+		t.Errorf("expected unsigned device event at its own endpoint, got %v", got)
+	}
+	if signed, ok := got["global 60111111111"]; !ok || !signed {
+		t.Errorf("expected other device at signed global endpoint, got %v", got)
+	}
+
+	// Clearing the endpoint restores the global URL
+	d.SetDeviceEndpoint("60123456789", "", "")
+	d.Send("device.connected", "60123456789", nil)
+	select {
+	case h := <-received:
+		if h.target != "global 60123456789" {
+			t.Fatalf("expected global delivery after clearing, got %+v", h)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for global delivery")
+	}
+}
+
+func TestDispatcher_SignedHeaders(t *testing.T) {
+	type request struct {
+		header http.Header
+		body   []byte
+	}
+	received := make(chan request, 10)
+	var calls atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		received <- request{header: r.Header.Clone(), body: body}
+		if calls.Add(1) == 1 {
+			t.Logf("failing %s", r.Header.Get("X-Webhook-Id"))
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer srv.Close()
+
+	d, err := NewDispatcher(Config{
+		URL:         srv.URL,
+		Secrets:     []string{"new-secret", "old-secret"},
+		Timeout:     time.Second,
+		MaxAttempts: 3,
+		RetryBase:   10 * time.Millisecond,
+		DBPath:      filepath.Join(t.TempDir(), "webhooks.db"),
+	}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+	d.Start()
+	d.Send("device.connected", "60123456789", nil)
+
+	var reqs []request
+	for len(reqs) < 2 {
+		select {
+		case r := <-received:
+			reqs = append(reqs, r)
+		case <-time.After(5 * time.Second):
+			t.Fatalf("timed out after %d requests", len(reqs))
+		}
+	}
+
+	id := reqs[0].header.Get(webhooksig.HeaderID)
+	if id == "" || reqs[1].header.Get(webhooksig.HeaderID) != id {
+		t.Fatalf("expected the same delivery ID on retry, got %q and %q", id, reqs[1].header.Get(webhooksig.HeaderID))
+	}
+	for _, secrets := range [][]string{{"new-secret"}, {"old-secret"}} {
+		if err := webhooksig.Verify(reqs[1].header, reqs[1].body, secrets, 0); err != nil {
+			t.Errorf("verify with %v failed: %v", secrets, err)
+		}
+	}
+	if err := webhooksig.Verify(reqs[1].header, reqs[1].body, []string{"other"}, 0); err != webhooksig.ErrNoSignature {
+		t.Errorf("expected ErrNoSignature for unknown secret, got %v", err)
+	}
+	if got, want := reqs[1].header.Get("X-Webhook-Signature"), legacySignature("new-secret", reqs[1].body); got != want {
+		t.Errorf("expected legacy signature %q with the first secret, got %q", want, got)
+	}
+}
+
+func TestDispatcher_SecretIsLiteral(t *testing.T) {
+	received := make(chan http.Header, 1)
+	var body []byte
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		received <- r.Header.Clone()
+	}))
+	defer srv.Close()
+
+	d, err := NewDispatcher(Config{URL: srv.URL, Secret: "a,b", Timeout: time.Second, DBPath: filepath.Join(t.TempDir(), "webhooks.db")}, zerolog.New(io.Discard))
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	defer func() { _ = d.Close() }()
+	d.Start()
+	d.Send("device.connected", "60123456789", nil)
+
+	var header http.Header
+	select {
+	case header = <-received:
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+	// Receivers from before signature versioning verify the bare HMAC of the
+	// body with the whole secret, commas included
+	if got, want := header.Get("X-Webhook-Signature"), legacySignature("a,b", body); got != want {
+		t.Errorf("expected legacy signature %q, got %q", want, got)
+	}
+	if err := webhooksig.Verify(header, body, []string{"a,b"}, 0); err != nil {
+		t.Errorf("verify with the whole secret failed: %v", err)
+	}
+	if err := webhooksig.Verify(header, body, []string{"a"}, 0); err != webhooksig.ErrNoSignature {
+		t.Errorf("expected ErrNoSignature for part of the secret, got %v", err)
+	}
+}
@@ -0,0 +1,178 @@
+package whatsapp
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/optout"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/rules"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/schedule"
+)
+
+const replyTimeout = 30 * time.Second
+
+func (s *DeviceSession) handleIncomingMessage(v *events.Message) {
+	phone := s.senderPhone(v.Info)
+
+	// Look the contact up before capturing it so first-contact rules can fire
+	known, err := s.Contacts.Get(phone)
+	if err != nil {
+		s.logger.Error().Err(err).Str("phone", phone).Msg("failed to look up contact")
+	}
+	s.captureContact(phone, v.Info.PushName)
+
+	// Opt-outs and auto-replies only apply to 1:1 chats
+	if v.Info.IsGroup || v.Info.Chat.Server == types.BroadcastServer {
+		return
+	}
+
+	text := messageText(v.Message)
+	if keyword := optout.MatchKeyword(text, s.optOutKeywords); keyword != "" {
+		s.optOut(phone, v.Info.PushName, keyword)
+		return
+	}
+
+	hours := s.businessHours()
+	in := rules.Inbound{
+		Text:         text,
+		FirstContact: err == nil && known == nil,
+		BusinessOpen: hours.IsOpen(v.Info.Timestamp),
+		Time:         v.Info.Timestamp,
+	}
+
+	// Event handlers run synchronously in whatsmeow, so reply off the event loop
+	go s.autoReply(v.Info.Chat, phone, v.Info.PushName, hours, in)
+}
+
+// senderPhone returns the phone number of the message's sender. Senders
+// addressed by LID are resolved through the message's alternate address or
+// the device's LID mapping.
+func (s *DeviceSession) senderPhone(info types.MessageInfo) string {
+	if info.Sender.Server == types.HiddenUserServer && info.SenderAlt.Server == types.DefaultUserServer {
+		return info.SenderAlt.User
+	}
+	return s.phoneOf(context.Background(), info.Sender)
+}
+
+// autoReply sends the first matching rule's reply. If no rule matches and the
+// message arrived outside business hours, the away message is sent instead.
+func (s *DeviceSession) autoReply(chat types.JID, phone, name string, hours schedule.BusinessHours, in rules.Inbound) {
+	list, err := s.Rules.List()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to load auto-reply rules")
+		return
+	}
+
+	if rule := rules.Evaluate(list, in); rule != nil {
+		text, err := s.renderReply(rule, name, phone)
+		if err != nil {
+			s.logger.Error().Err(err).Str("rule", rule.ID).Msg("failed to render auto-reply")
+			return
+		}
+		s.sendAutoReply(chat, phone, text, map[string]interface{}{
+			"type":     "rule",
+			"ruleId":   rule.ID,
+			"ruleName": rule.Name,
+		})
+		return
+	}
+
+	if in.BusinessOpen || hours.AwayMessage == "" || !s.awayDue(phone, hours, in.Time) {
+		return
+	}
+	if !s.sendAutoReply(chat, phone, hours.AwayMessage, map[string]interface{}{
+		"type": "away",
+	}) {
+		return
+	}
+	if err := s.Contacts.MarkAwaySent(phone, time.Now()); err != nil {
+		s.logger.Error().Err(err).Str("phone", phone).Msg("failed to record away message")
+	}
+}
+
+// sendAutoReply sends text to the chat and reports whether it was sent.
+func (s *DeviceSession) sendAutoReply(chat types.JID, phone, text string, data map[string]interface{}) bool {
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
+
+	result, err := s.sendText(ctx, chat, text, nil)
+	if errors.Is(err, ErrRecipientOptedOut) {
+		s.logger.Debug().Str("to", phone).Msg("auto-reply skipped, recipient opted out")
+		return false
+	}
+	if err != nil {
+		s.logger.Error().Err(err).Interface("reply", data).Msg("failed to send auto-reply")
+		return false
+	}
+
+	s.logger.Debug().Interface("reply", data).Str("to", phone).Msg("auto-reply sent")
+	data["to"] = phone
+	data["messageId"] = result.ID
+	s.webhook.Send("message.autoreply", s.Token, data)
+	return true
+}
+
+// awayDue reports whether the contact should get the away message, i.e. it
+// has not been sent one since the current closed window began.
+func (s *DeviceSession) awayDue(phone string, hours schedule.BusinessHours, now time.Time) bool {
+	sentAt, err := s.Contacts.AwaySentAt(phone)
+	if err != nil {
+		s.logger.Error().Err(err).Str("phone", phone).Msg("failed to look up away message")
+		return false
+	}
+	return sentAt.IsZero() || sentAt.Before(hours.ClosedSince(now))
+}
+
+func (s *DeviceSession) businessHours() schedule.BusinessHours {
+	st, err := s.Settings.Get()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to load device settings")
+		return schedule.BusinessHours{}
+	}
+	return st.BusinessHours
+}
+
+func (s *DeviceSession) optOut(phone, name, keyword string) {
+	added, err := s.OptOuts.Add(phone, "keyword", keyword)
+	if err != nil {
+		s.logger.Error().Err(err).Str("phone", phone).Msg("failed to record opt-out")
+		return
+	}
+	if !added {
+		return
+	}
+
+	s.logger.Info().Str("phone", phone).Str("keyword", keyword).Msg("contact opted out")
+	s.webhook.Send("contact.opted_out", s.Token, map[string]string{
+		"phone":   phone,
+		"name":    name,
+		"source":  "keyword",
+		"keyword": keyword,
+	})
+}
+
+func (s *DeviceSession) renderReply(rule *rules.Rule, name, phone string) (string, error) {
+	if rule.Reply.Type == rules.ReplyText {
+		return rule.Reply.Text, nil
+	}
+
+	tmpl, err := s.Rules.GetTemplate(rule.Reply.Template)
+	if err != nil {
+		return "", fmt.Errorf("template %q: %w", rule.Reply.Template, err)
+	}
+	return tmpl.Render(name, phone), nil
+}
+
+// messageText extracts the plain text body of a message, if it has one.
+func messageText(msg *waE2E.Message) string {
+	if text := msg.GetConversation(); text != "" {
+		return text
+	}
+	return msg.GetExtendedTextMessage().GetText()
+}
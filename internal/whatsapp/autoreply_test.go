@@ -0,0 +1,162 @@
+package whatsapp
+
+import (
+	"errors"
+	"io"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/rs/zerolog"
+	"go.mau.fi/whatsmeow"
+	"go.mau.fi/whatsmeow/proto/waE2E"
+	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+	waLog "go.mau.fi/whatsmeow/util/log"
+	"google.golang.org/protobuf/proto"
+
+	"github.com/AsyrafHussin/wa-gateway-go/config"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/rules"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/schedule"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/webhook"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/ws"
+)
+
+func newTestSession(t *testing.T) *DeviceSession {
+	t.Helper()
+	logger := zerolog.New(io.Discard)
+	dispatcher, err := webhook.NewDispatcher(webhook.Config{}, logger)
+	if err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	cfg := &config.Config{DataDir: t.TempDir(), OptOutKeywords: "STOP"}
+	s, err := NewDeviceSession("60123456789", cfg, ws.NewHub(logger), dispatcher, logger)
+	if err != nil {
+		t.Fatalf("failed to create session: %v", err)
+	}
+	t.Cleanup(s.closeStores)
+	return s
+}
+
+func TestHandleIncomingMessage_LIDSender(t *testing.T) {
+	s := newTestSession(t)
+
+	lid := types.NewJID("123456789012345", types.HiddenUserServer)
+	s.handleIncomingMessage(&events.Message{
+		Info: types.MessageInfo{
+			MessageSource: types.MessageSource{
+				Chat:      lid,
+				Sender:    lid,
+				SenderAlt: types.NewJID("60198765432", types.DefaultUserServer),
+			},
+			PushName:  "Ali",
+			Timestamp: time.Now(),
+		},
+		Message: &waE2E.Message{Conversation: proto.String("stop")},
+	})
+
+	if contact, err := s.Contacts.Get("60198765432"); err != nil || contact == nil {
+		t.Errorf("expected contact recorded under the phone number, got %v err=%v", contact, err)
+	}
+	if opted, err := s.OptOuts.Has("60198765432"); err != nil || !opted {
+		t.Errorf("expected opt-out recorded under the phone number, got %v err=%v", opted, err)
+	}
+	if contact, _ := s.Contacts.Get(lid.User); contact != nil {
+		t.Errorf("expected no contact recorded under the LID, got %+v", contact)
+	}
+}
+
+func TestSenderPhone(t *testing.T) {
+	s := newTestSession(t)
+
+	pn := types.NewJID("60198765432", types.DefaultUserServer)
+	lid := types.NewJID("123456789012345", types.HiddenUserServer)
+	tests := []struct {
+		name   string
+		source types.MessageSource
+		want   string
+	}{
+		{"phone number sender", types.MessageSource{Sender: pn, SenderAlt: lid}, "60198765432"},
+		{"LID sender", types.MessageSource{Sender: lid, SenderAlt: pn}, "60198765432"},
+		{"unresolved LID sender", types.MessageSource{Sender: lid}, "123456789012345"},
+	}
+	for _, tt := range tests {
+		if got := s.senderPhone(types.MessageInfo{MessageSource: tt.source}); got != tt.want {
+			t.Errorf("%s: expected %q, got %q", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestAwayMessage_SentOncePerClosedWindow(t *testing.T) {
+	s := newTestSession(t)
+	hours := schedule.BusinessHours{
+		Enabled:     true,
+		Timezone:    "UTC",
+		Weekly:      map[string][]schedule.Period{"mon": {{Start: "09:00", End: "17:00"}}, "tue": {{Start: "09:00", End: "17:00"}}},
+		AwayMessage: "We are closed",
+	}
+	now := time.Date(2026, 10, 19, 20, 0, 0, 0, time.UTC) // Monday, closed since 17:00
+	phone := "60198765432"
+
+	// A contact captured from history sync has a fresh last_seen but was
+	// never sent the away message
+	if err := s.Contacts.Upsert(phone, "Ali", "history"); err != nil {
+		t.Fatalf("failed to upsert contact: %v", err)
+	}
+	if !s.awayDue(phone, hours, now) {
+		t.Error("expected away message due for a history-synced contact")
+	}
+
+	// A failed send is not recorded, so the next message tries again
+	if _, err := s.OptOuts.Add(phone, "api", ""); err != nil {
+		t.Fatalf("failed to add opt-out: %v", err)
+	}
+	s.autoReply(types.NewJID(phone, types.DefaultUserServer), phone, "Ali", hours, rules.Inbound{Text: "hi", Time: now})
+	if sentAt, err := s.Contacts.AwaySentAt(phone); err != nil || !sentAt.IsZero() {
+		t.Errorf("expected no away message recorded after a failed send, got %v err=%v", sentAt, err)
+	}
+	if !s.awayDue(phone, hours, now) {
+		t.Error("expected away message still due after a failed send")
+	}
+
+	if err := s.Contacts.MarkAwaySent(phone, now); err != nil {
+		t.Fatalf("failed to mark away message sent: %v", err)
+	}
+	if s.awayDue(phone, hours, now.Add(time.Hour)) {
+		t.Error("expected no away message twice in the same closed window")
+	}
+	if !s.awayDue(phone, hours, now.Add(24*time.Hour)) {
+		t.Error("expected away message due in the next closed window")
+	}
+}
+
+func TestAutoReply_RefusesOptedOutLIDRecipient(t *testing.T) {
+	s := newTestSession(t)
+
+	// A client that is never connected, only used for its LID mapping
+	container, err := sqlstore.New(t.Context(), "sqlite", "file:"+filepath.Join(t.TempDir(), "session.db")+"?_pragma=foreign_keys(1)", waLog.Noop)
+	if err != nil {
+		t.Fatalf("failed to create device store: %v", err)
+	}
+	t.Cleanup(func() { _ = container.Close() })
+	device := container.NewDevice()
+	device.LIDs = container.LIDMap
+	s.Client = whatsmeow.NewClient(device, waLog.Noop)
+
+	pn := types.NewJID("60198765432", types.DefaultUserServer)
+	lid := types.NewJID("123456789012345", types.HiddenUserServer)
+	if err := s.Client.Store.LIDs.PutLIDMapping(t.Context(), lid, pn); err != nil {
+		t.Fatalf("failed to store LID mapping: %v", err)
+	}
+	if _, err := s.OptOuts.Add(pn.User, "keyword", "STOP"); err != nil {
+		t.Fatalf("failed to add opt-out: %v", err)
+	}
+
+	if _, err := s.sendText(t.Context(), lid, "Thanks for your message", nil); !errors.Is(err, ErrRecipientOptedOut) {
+		t.Fatalf("expected ErrRecipientOptedOut for the LID chat, got %v", err)
+	}
+	if s.sendAutoReply(lid, pn.User, "Thanks for your message", map[string]interface{}{"type": "away"}) {
+		t.Error("expected auto-reply to an opted-out LID chat not to be sent")
+	}
+}
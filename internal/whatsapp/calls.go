@@ -0,0 +1,68 @@
+package whatsapp
+
+import (
+	"context"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/settings"
+)
+
+func (s *DeviceSession) handleCallOffer(meta types.BasicCallMeta, platform string, isVideo bool) {
+	policy := s.callPolicy()
+
+	s.webhook.Send("call.incoming", s.Token, map[string]interface{}{
+		"callId":       meta.CallID,
+		"from":         meta.From.String(),
+		"isGroup":      !meta.GroupJID.IsEmpty(),
+		"isVideo":      isVideo,
+		"platform":     platform,
+		"autoRejected": policy.AutoReject,
+		"timestamp":    meta.Timestamp,
+	})
+
+	if !policy.AutoReject {
+		return
+	}
+
+	// Event handlers run synchronously in whatsmeow, so reject and reply off the event loop
+	go s.rejectCall(meta, policy.RejectMessage)
+}
+
+func (s *DeviceSession) rejectCall(meta types.BasicCallMeta, message string) {
+	ctx, cancel := context.WithTimeout(context.Background(), replyTimeout)
+	defer cancel()
+
+	if err := s.Client.RejectCall(ctx, meta.From, meta.CallID); err != nil {
+		s.logger.Error().Err(err).Str("callId", meta.CallID).Msg("failed to reject call")
+		return
+	}
+	s.logger.Info().Str("callId", meta.CallID).Str("from", meta.From.String()).Msg("call auto-rejected")
+
+	// Group calls are rejected silently
+	if message == "" || !meta.GroupJID.IsEmpty() {
+		return
+	}
+	if _, err := s.sendText(ctx, meta.From.ToNonAD(), message, nil); err != nil {
+		s.logger.Error().Err(err).Str("callId", meta.CallID).Msg("failed to send call reject reply")
+	}
+}
+
+func (s *DeviceSession) handleCallTerminate(v *events.CallTerminate) {
+	s.webhook.Send("call.ended", s.Token, map[string]interface{}{
+		"callId":    v.CallID,
+		"from":      v.From.String(),
+		"reason":    v.Reason,
+		"timestamp": v.Timestamp,
+	})
+}
+
+func (s *DeviceSession) callPolicy() settings.CallPolicy {
+	st, err := s.Settings.Get()
+	if err != nil {
+		s.logger.Error().Err(err).Msg("failed to load device settings")
+		return settings.CallPolicy{}
+	}
+	return st.Calls
+}
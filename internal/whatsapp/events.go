@@ -4,55 +4,81 @@ import (
 	"context"
 	"strings"
 
-	"go.mau.fi/whatsmeow/types"
 	"go.mau.fi/whatsmeow/types/events"
 )
 
 func (s *DeviceSession) handleEvent(evt interface{}) {
 	switch v := evt.(type) {
 	case *events.Connected:
-		s.setStatus(StatusConnected)
+		s.setConnected()
 		s.logger.Info().Msg("connected to WhatsApp")
 		s.hub.Broadcast(s.Token, "connection-success", nil)
 		s.webhook.Send("device.connected", s.Token, nil)
-		if err := s.Client.SendPresence(context.Background(), types.PresenceAvailable); err != nil {
+		if err := s.Client.SendPresence(context.Background(), s.getPresence()); err != nil {
 			s.logger.Error().Err(err).Msg("failed to send presence")
 		}
 
 	case *events.Disconnected:
-		s.setStatus(StatusDisconnected)
+		s.setDisconnected("connection lost")
 		s.logger.Warn().Msg("disconnected from WhatsApp")
 		s.hub.BroadcastWithMessage(s.Token, "connection-error", "Disconnected")
 		s.webhook.Send("device.disconnected", s.Token, nil)
 
 	case *events.LoggedOut:
-		s.setStatus(StatusDisconnected)
+		reason := "logged out"
+		if v.OnConnect {
+			reason += ": " + v.Reason.String()
+		}
+		s.setDisconnected(reason)
 		s.logger.Warn().Msg("logged out from WhatsApp")
 		s.hub.BroadcastWithMessage(s.Token, "connection-error", "Logged out")
-		s.webhook.Send("device.logged_out", s.Token, nil)
+		s.webhook.Send("device.logged_out", s.Token, map[string]interface{}{
+			"reason": reason,
+		})
 
 	case *events.Message:
 		if !v.Info.IsFromMe {
-			s.captureContact(v.Info.Sender.User, v.Info.PushName)
+			s.stats.received.Add(1)
+			s.handleIncomingMessage(v)
 		}
 
 	case *events.Receipt:
 		s.webhook.Send("message.receipt", s.Token, map[string]interface{}{
 			"type":       string(v.Type),
 			"messageIds": v.MessageIDs,
+			"chat":       v.Chat.String(),
 			"from":       v.Sender.String(),
 			"timestamp":  v.Timestamp,
 		})
 
+	case *events.Presence:
+		s.handlePresence(v)
+
+	case *events.ChatPresence:
+		s.handleChatPresence(v)
+
+	case *events.CallOffer:
+		isVideo := false
+		if v.Data != nil {
+			_, isVideo = v.Data.GetOptionalChildByTag("video")
+		}
+		s.handleCallOffer(v.BasicCallMeta, v.RemotePlatform, isVideo)
+
+	case *events.CallOfferNotice:
+		s.handleCallOffer(v.BasicCallMeta, "", v.Media == "video")
+
+	case *events.CallTerminate:
+		s.handleCallTerminate(v)
+
 	case *events.HistorySync:
 		go s.processHistorySync(v)
 
 	case *events.StreamReplaced:
-		s.setStatus(StatusDisconnected)
+		s.setDisconnected("stream replaced by another client")
 		s.logger.Warn().Msg("stream replaced by another client")
 
 	case *events.ConnectFailure:
-		s.setStatus(StatusDisconnected)
+		s.setDisconnected("connection failure: " + v.Reason.String())
 		s.logger.Error().Str("reason", v.Reason.String()).Msg("connection failure")
 		s.hub.BroadcastWithMessage(s.Token, "connection-error", "Connection failed: "+v.Reason.String())
 
@@ -61,6 +87,7 @@ func (s *DeviceSession) handleEvent(evt interface{}) {
 
 	case *events.PairSuccess:
 		s.logger.Info().Msg("pairing successful")
+		s.clearPairing()
 	}
 }
 
@@ -0,0 +1,21 @@
+package whatsapp
+
+import "fmt"
+
+// AddSession registers a disconnected session for the device, opening its
+// stores without connecting to WhatsApp, so tests can drive handlers that
+// need an existing device.
+func (m *DeviceManager) AddSession(token string) (*DeviceSession, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if existing, ok := m.sessions[token]; ok {
+		return existing, nil
+	}
+
+	session, err := NewDeviceSession(token, m.config, m.hub, m.webhook, m.logger)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create session: %w", err)
+	}
+	m.sessions[token] = session
+	return session, nil
+}
@@ -0,0 +1,240 @@
+package whatsapp_test
+
+import (
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gofiber/fiber/v2"
+	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/config"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/handler"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/middleware"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/settings"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/webhook"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/ws"
+)
+
+// These tests drive REST handlers against a device session that exists
+// without connecting to WhatsApp, which only tests in this package can add.
+
+const (
+	testToken     = "60123456789"
+	testMasterKey = "master-key"
+)
+
+// testGateway is a device manager holding one disconnected session for
+// testToken, behind an API authenticated with testMasterKey or named keys.
+type testGateway struct {
+	t          *testing.T
+	dir        string
+	logger     zerolog.Logger
+	keys       *apikey.Store
+	dispatcher *webhook.Dispatcher
+	manager    *whatsapp.DeviceManager
+	session    *whatsapp.DeviceSession
+	app        *fiber.App
+	api        fiber.Router
+}
+
+func newTestGateway(t *testing.T, webhookCfg webhook.Config) *testGateway {
+	t.Helper()
+	g := &testGateway{t: t, dir: t.TempDir(), logger: zerolog.New(io.Discard)}
+
+	var err error
+	if g.keys, err = apikey.NewStore(filepath.Join(g.dir, "keys.db")); err != nil {
+		t.Fatalf("failed to create key store: %v", err)
+	}
+	t.Cleanup(func() { _ = g.keys.Close() })
+
+	if g.dispatcher, err = webhook.NewDispatcher(webhookCfg, g.logger); err != nil {
+		t.Fatalf("failed to create dispatcher: %v", err)
+	}
+	t.Cleanup(func() { _ = g.dispatcher.Close() })
+
+	g.manager = whatsapp.NewDeviceManager(&config.Config{DataDir: g.dir, OptOutKeywords: "STOP"}, ws.NewHub(g.logger), g.dispatcher, g.logger)
+	t.Cleanup(func() { g.manager.ShutdownAll(t.Context()) })
+	g.session = g.addSession()
+
+	g.app = fiber.New(fiber.Config{DisableStartupMessage: true})
+	g.api = g.app.Group("", middleware.NewAuth(testMasterKey, g.keys, nil).Require())
+	return g
+}
+
+// addSession opens testToken's session, e.g. again after a logout.
+func (g *testGateway) addSession() *whatsapp.DeviceSession {
+	g.t.Helper()
+	session, err := g.manager.AddSession(testToken)
+	if err != nil {
+		g.t.Fatalf("failed to add session: %v", err)
+	}
+	return session
+}
+
+func (g *testGateway) do(method, path, key, body string) (int, string) {
+	g.t.Helper()
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-API-Key", key)
+	resp, err := g.app.Test(req)
+	if err != nil {
+		g.t.Fatalf("request failed: %v", err)
+	}
+	b, _ := io.ReadAll(resp.Body)
+	return resp.StatusCode, string(b)
+}
+
+func TestRules_RejectsInvalidRules(t *testing.T) {
+	g := newTestGateway(t, webhook.Config{})
+	h := handler.NewRules(g.manager, g.logger)
+	g.api.Post("/rules/:token", h.Create)
+	g.api.Put("/rules/:token/:id", h.Update)
+
+	valid := `{"name":"price","match":"keyword","pattern":"price","reply":{"type":"text","text":"RM10"}}`
+	if code, body := g.do(http.MethodPost, "/rules/"+testToken, testMasterKey, valid); code != http.StatusCreated {
+		t.Fatalf("expected valid rule to be created, got %d %s", code, body)
+	}
+	list, _ := g.session.Rules.List()
+	if len(list) != 1 {
+		t.Fatalf("expected 1 rule, got %d", len(list))
+	}
+	id := list[0].ID
+
+	tests := []struct {
+		name, method, path, body, code string
+	}{
+		{"invalid rule", http.MethodPost, "/rules/" + testToken, `{"name":"bad","match":"regex","pattern":"(","reply":{"type":"text","text":"x"}}`, "INVALID_RULE"},
+		{"missing template", http.MethodPost, "/rules/" + testToken, `{"name":"tpl","match":"keyword","pattern":"hi","reply":{"type":"template","template":"nope"}}`, "TEMPLATE_NOT_FOUND"},
+		{"invalid update", http.MethodPut, "/rules/" + testToken + "/" + id, `{"name":"price","match":"keyword","pattern":"","reply":{"type":"text","text":"x"}}`, "INVALID_RULE"},
+		{"update to missing template", http.MethodPut, "/rules/" + testToken + "/" + id, `{"name":"price","match":"keyword","pattern":"price","reply":{"type":"template","template":"nope"}}`, "TEMPLATE_NOT_FOUND"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			code, body := g.do(tt.method, tt.path, testMasterKey, tt.body)
+			if code != http.StatusBadRequest || !strings.Contains(body, tt.code) {
+				t.Fatalf("expected 400 %s, got %d %s", tt.code, code, body)
+			}
+		})
+	}
+
+	list, err := g.session.Rules.List()
+	if err != nil || len(list) != 1 {
+		t.Fatalf("expected only the valid rule to be stored, got %+v err=%v", list, err)
+	}
+	if r := list[0]; r.Pattern != "price" || r.Reply.Type != "text" || r.Reply.Text != "RM10" {
+		t.Errorf("expected stored rule to be unchanged, got %+v", r)
+	}
+}
+
+func TestSettings_WebhookURLRequiresAdmin(t *testing.T) {
+	g := newTestGateway(t, webhook.Config{})
+	writer, err := g.keys.Create(&apikey.Key{Name: "tenant-app", Tenant: "acme", Scopes: []string{apikey.ScopeDevicesWrite}})
+	if err != nil {
+		t.Fatalf("failed to create key: %v", err)
+	}
+	if _, err := g.keys.Claim(testToken, "acme"); err != nil {
+		t.Fatalf("failed to claim device: %v", err)
+	}
+
+	devicesWrite := middleware.Scope(apikey.ScopeDevicesWrite)
+	g.api.Post("/devices", devicesWrite, handler.NewDevice(g.manager, g.keys, g.logger).Connect)
+	g.api.Patch("/devices/:token/settings", devicesWrite, handler.NewSettings(g.manager, g.logger).Update)
+
+	internal := `{"webhook":{"url":"http://169.254.169.254/latest/meta-data"}}`
+	if code, body := g.do(http.MethodPatch, "/devices/"+testToken+"/settings", writer, internal); code != http.StatusForbidden || !strings.Contains(body, "INSUFFICIENT_SCOPE") {
+		t.Fatalf("expected 403 INSUFFICIENT_SCOPE for settings, got %d %s", code, body)
+	}
+	if code, body := g.do(http.MethodPost, "/devices", writer, `{"token":"60198765432","webhook":{"url":"http://127.0.0.1:8080/hook"}}`); code != http.StatusForbidden || !strings.Contains(body, "INSUFFICIENT_SCOPE") {
+		t.Fatalf("expected 403 INSUFFICIENT_SCOPE for connect, got %d %s", code, body)
+	}
+	if st, _ := g.session.Settings.Get(); st.Webhook.URL != "" {
+		t.Fatalf("expected webhook URL unchanged, got %q", st.Webhook.URL)
+	}
+
+	// Other settings remain writable, and admins may set the URL
+	if code, body := g.do(http.MethodPatch, "/devices/"+testToken+"/settings", writer, `{"calls":{"autoReject":true}}`); code != http.StatusOK {
+		t.Fatalf("expected 200 for other settings, got %d %s", code, body)
+	}
+	if code, body := g.do(http.MethodPatch, "/devices/"+testToken+"/settings", testMasterKey, `{"webhook":{"url":"https://hooks.example.com/wa"}}`); code != http.StatusOK {
+		t.Fatalf("expected 200 for admin, got %d %s", code, body)
+	}
+	if code, body := g.do(http.MethodPatch, "/devices/"+testToken+"/settings", writer, `{"calls":{"autoReject":false}}`); code != http.StatusOK {
+		t.Fatalf("expected 200 when webhook URL is unchanged, got %d %s", code, body)
+	}
+}
+
+func TestDevice_DisconnectDeletesDeviceData(t *testing.T) {
+	// The dispatcher is not started, so deliveries stay in the outbox
+	dbPath := filepath.Join(t.TempDir(), "webhooks.db")
+	g := newTestGateway(t, webhook.Config{URL: "http://global.example/hook", DBPath: dbPath})
+	g.api.Delete("/devices/:token", handler.NewDevice(g.manager, g.keys, g.logger).Disconnect)
+
+	if _, err := g.keys.Claim(testToken, "tenant-a"); err != nil {
+		t.Fatalf("failed to claim for tenant-a: %v", err)
+	}
+	if err := g.session.SetWebhook(settings.Webhook{URL: "http://tenant-a.example/hook", Secret: "a-secret"}); err != nil {
+		t.Fatalf("failed to set webhook: %v", err)
+	}
+
+	if code, body := g.do(http.MethodDelete, "/devices/"+testToken, testMasterKey, ""); code != http.StatusOK {
+		t.Fatalf("expected disconnect to succeed, got %d %s", code, body)
+	}
+
+	claimed, err := g.keys.Claim(testToken, "tenant-b")
+	if err != nil || !claimed {
+		t.Fatalf("expected tenant-b to claim the released device, got %v err=%v", claimed, err)
+	}
+	session := g.addSession()
+	st, err := session.Settings.Get()
+	if err != nil {
+		t.Fatalf("failed to get settings: %v", err)
+	}
+	if st.Webhook.URL != "" || st.Webhook.Secret != "" {
+		t.Fatalf("expected default settings for tenant-b, got webhook %+v", st.Webhook)
+	}
+
+	g.dispatcher.Send("message.received", testToken, map[string]string{"text": "hi"})
+	outbox, err := webhook.NewOutbox(dbPath)
+	if err != nil {
+		t.Fatalf("failed to open outbox: %v", err)
+	}
+	defer func() { _ = outbox.Close() }()
+	due, err := outbox.Due(time.Now().Add(time.Hour), 10)
+	if err != nil {
+		t.Fatalf("failed to list deliveries: %v", err)
+	}
+	if len(due) != 1 || due[0].URL != "http://global.example/hook" {
+		t.Fatalf("expected one delivery to the global endpoint, got %+v", due)
+	}
+}
+
+func TestDevice_DisconnectKeepsOptOuts(t *testing.T) {
+	g := newTestGateway(t, webhook.Config{})
+	g.api.Delete("/devices/:token", handler.NewDevice(g.manager, g.keys, g.logger).Disconnect)
+
+	if _, err := g.keys.Claim(testToken, "tenant-a"); err != nil {
+		t.Fatalf("failed to claim for tenant-a: %v", err)
+	}
+	if _, err := g.session.OptOuts.Add("60198765432", "keyword", "STOP"); err != nil {
+		t.Fatalf("failed to add opt-out: %v", err)
+	}
+
+	if code, body := g.do(http.MethodDelete, "/devices/"+testToken, testMasterKey, ""); code != http.StatusOK {
+		t.Fatalf("expected disconnect to succeed, got %d %s", code, body)
+	}
+
+	if _, err := g.keys.Claim(testToken, "tenant-a"); err != nil {
+		t.Fatalf("failed to claim again for tenant-a: %v", err)
+	}
+	session := g.addSession()
+	if opted, err := session.OptOuts.Has("60198765432"); err != nil || !opted {
+		t.Fatalf("expected opt-out to survive the logout, got %v err=%v", opted, err)
+	}
+}
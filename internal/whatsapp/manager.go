@@ -5,19 +5,35 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"sort"
 	"strings"
 	"sync"
+	"time"
 
 	"github.com/rs/zerolog"
 
 	"github.com/AsyrafHussin/wa-gateway-go/config"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/settings"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/webhook"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/ws"
 )
 
 type SessionInfo struct {
-	Token  string `json:"token"`
-	Status string `json:"status"`
+	Token                string          `json:"token"`
+	Status               string          `json:"status"`
+	JID                  string          `json:"jid,omitempty"`
+	PushName             string          `json:"pushName,omitempty"`
+	Platform             string          `json:"platform,omitempty"`
+	ConnectedSince       *time.Time      `json:"connectedSince,omitempty"`
+	LastDisconnectReason string          `json:"lastDisconnectReason,omitempty"`
+	Messages             MessageCounters `json:"messages"`
+}
+
+// MessageCounters count messages since the session was created.
+type MessageCounters struct {
+	Sent     int64 `json:"sent"`
+	Received int64 `json:"received"`
+	Failed   int64 `json:"failed"`
 }
 
 type DeviceManager struct {
@@ -39,7 +55,10 @@ func NewDeviceManager(cfg *config.Config, hub *ws.Hub, dispatcher *webhook.Dispa
 	}
 }
 
-func (m *DeviceManager) Connect(ctx context.Context, token string, method string) error {
+// Connect creates a session and starts connecting it. A non-nil webhook is
+// saved to the device settings before connecting, so the first events already
+// go to the device's own endpoint.
+func (m *DeviceManager) Connect(ctx context.Context, token string, method string, webhook *settings.Webhook) error {
 	m.mu.Lock()
 	if existing, ok := m.sessions[token]; ok {
 		if existing.GetStatus() == StatusConnected {
@@ -56,6 +75,13 @@ func (m *DeviceManager) Connect(ctx context.Context, token string, method string
 		m.mu.Unlock()
 		return fmt.Errorf("failed to create session: %w", err)
 	}
+	if webhook != nil {
+		if err := session.SetWebhook(*webhook); err != nil {
+			m.mu.Unlock()
+			session.Disconnect(ctx)
+			return fmt.Errorf("failed to save webhook: %w", err)
+		}
+	}
 	m.sessions[token] = session
 	m.mu.Unlock()
 
@@ -84,7 +110,7 @@ func (m *DeviceManager) Disconnect(ctx context.Context, token string) error {
 	return nil
 }
 
-func (m *DeviceManager) SendText(ctx context.Context, token, to, text string) (*SendResult, error) {
+func (m *DeviceManager) SendText(ctx context.Context, token, to, text string, typing *Typing) (*SendResult, error) {
 	session, ok := m.GetSession(token)
 	if !ok {
 		return nil, fmt.Errorf("device not found")
@@ -92,7 +118,40 @@ func (m *DeviceManager) SendText(ctx context.Context, token, to, text string) (*
 	if session.GetStatus() != StatusConnected {
 		return nil, fmt.Errorf("device not connected")
 	}
-	return session.SendText(ctx, to, text)
+	return session.SendText(ctx, to, text, typing)
+}
+
+func (m *DeviceManager) SetPresence(ctx context.Context, token string, available bool) error {
+	session, ok := m.GetSession(token)
+	if !ok {
+		return fmt.Errorf("device not found")
+	}
+	if session.GetStatus() != StatusConnected {
+		return fmt.Errorf("device not connected")
+	}
+	return session.SetPresence(ctx, available)
+}
+
+func (m *DeviceManager) SubscribePresence(ctx context.Context, token, to string) error {
+	session, ok := m.GetSession(token)
+	if !ok {
+		return fmt.Errorf("device not found")
+	}
+	if session.GetStatus() != StatusConnected {
+		return fmt.Errorf("device not connected")
+	}
+	return session.SubscribePresence(ctx, to)
+}
+
+func (m *DeviceManager) SendChatPresence(ctx context.Context, token, to, state string) error {
+	session, ok := m.GetSession(token)
+	if !ok {
+		return fmt.Errorf("device not found")
+	}
+	if session.GetStatus() != StatusConnected {
+		return fmt.Errorf("device not connected")
+	}
+	return session.SendChatPresence(ctx, to, state)
 }
 
 func (m *DeviceManager) ValidatePhone(ctx context.Context, token, phone string) ([]ValidateResult, error) {
@@ -118,12 +177,10 @@ func (m *DeviceManager) ListSessions() []SessionInfo {
 	defer m.mu.RUnlock()
 
 	infos := make([]SessionInfo, 0, len(m.sessions))
-	for token, s := range m.sessions {
-		infos = append(infos, SessionInfo{
-			Token:  token,
-			Status: s.GetStatus().String(),
-		})
+	for _, s := range m.sessions {
+		infos = append(infos, s.Info())
 	}
+	sort.Slice(infos, func(i, j int) bool { return infos[i].Token < infos[j].Token })
 	return infos
 }
 
@@ -154,7 +211,7 @@ func (m *DeviceManager) AutoReconnect(ctx context.Context) {
 		token := strings.TrimSuffix(entry.Name(), ".db")
 		m.logger.Info().Str("token", token).Msg("auto-reconnecting existing session")
 
-		if err := m.Connect(ctx, token, "reconnect"); err != nil {
+		if err := m.Connect(ctx, token, "reconnect", nil); err != nil {
 			m.logger.Error().Err(err).Str("token", token).Msg("failed to auto-reconnect")
 		}
 	}
@@ -0,0 +1,60 @@
+package whatsapp
+
+import "time"
+
+// pairingCodeTTL approximates how long WhatsApp accepts a pairing code.
+const pairingCodeTTL = 3 * time.Minute
+
+// Pairing is a QR or pairing code awaiting use, with when it stops being valid.
+type Pairing struct {
+	Code      string    `json:"code"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+func (p *Pairing) expired() bool {
+	return p == nil || time.Now().After(p.ExpiresAt)
+}
+
+// QR returns the latest unexpired QR code, or nil.
+func (s *DeviceSession) QR() *Pairing {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.qr.expired() {
+		return nil
+	}
+	return s.qr
+}
+
+// PairingCode returns the latest unexpired pairing code, or nil.
+func (s *DeviceSession) PairingCode() *Pairing {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.pairingCode.expired() {
+		return nil
+	}
+	return s.pairingCode
+}
+
+func (s *DeviceSession) setQR(code string, ttl time.Duration) *Pairing {
+	p := &Pairing{Code: code, ExpiresAt: time.Now().UTC().Add(ttl)}
+	s.mu.Lock()
+	s.qr = p
+	s.mu.Unlock()
+	return p
+}
+
+func (s *DeviceSession) setPairingCode(code string) *Pairing {
+	p := &Pairing{Code: code, ExpiresAt: time.Now().UTC().Add(pairingCodeTTL)}
+	s.mu.Lock()
+	s.pairingCode = p
+	s.mu.Unlock()
+	return p
+}
+
+// clearPairing drops codes once the device has paired or pairing ended.
+func (s *DeviceSession) clearPairing() {
+	s.mu.Lock()
+	s.qr = nil
+	s.pairingCode = nil
+	s.mu.Unlock()
+}
@@ -0,0 +1,154 @@
+package whatsapp
+
+import (
+	"context"
+	"fmt"
+	"time"
+	"unicode/utf8"
+
+	"go.mau.fi/whatsmeow/types"
+	"go.mau.fi/whatsmeow/types/events"
+)
+
+// Chat presence states accepted by SendChatPresence.
+const (
+	ChatStateComposing = "composing"
+	ChatStateRecording = "recording"
+	ChatStatePaused    = "paused"
+)
+
+// Typing simulation modes for outbound messages.
+const (
+	TypingOff          = "off"
+	TypingFixed        = "fixed"
+	TypingProportional = "proportional"
+)
+
+const (
+	// defaultMsPerChar is the proportional typing speed when no rate is given.
+	defaultMsPerChar = 50
+	// maxTypingDelay caps any simulated typing delay.
+	maxTypingDelay = 30 * time.Second
+)
+
+// Typing overrides the configured typing simulation for a single send.
+// Ms is the total delay for "fixed" and the per-character delay for "proportional".
+type Typing struct {
+	Mode string `json:"mode"`
+	Ms   int    `json:"ms"`
+}
+
+func (t *Typing) Validate() error {
+	switch t.Mode {
+	case TypingOff, TypingFixed, TypingProportional:
+	default:
+		return fmt.Errorf("mode must be %q, %q or %q", TypingOff, TypingFixed, TypingProportional)
+	}
+	if t.Ms < 0 {
+		return fmt.Errorf("ms must not be negative")
+	}
+	return nil
+}
+
+// typingDelay returns how long to show the typing indicator before sending text.
+// A nil override uses the configured TYPING_DELAY_MS.
+func (s *DeviceSession) typingDelay(text string, t *Typing) time.Duration {
+	var delay time.Duration
+	switch {
+	case t == nil:
+		delay = time.Duration(s.config.TypingDelay) * time.Millisecond
+	case t.Mode == TypingOff:
+		return 0
+	case t.Mode == TypingFixed:
+		delay = time.Duration(t.Ms) * time.Millisecond
+	case t.Mode == TypingProportional:
+		perChar := t.Ms
+		if perChar == 0 {
+			perChar = defaultMsPerChar
+		}
+		delay = time.Duration(utf8.RuneCountInString(text)*perChar) * time.Millisecond
+	}
+	return min(delay, maxTypingDelay)
+}
+
+// SetPresence sets the device's global presence. It is remembered and
+// re-sent whenever the device reconnects.
+func (s *DeviceSession) SetPresence(ctx context.Context, available bool) error {
+	presence := types.PresenceUnavailable
+	if available {
+		presence = types.PresenceAvailable
+	}
+	if err := s.Client.SendPresence(ctx, presence); err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	s.presence = presence
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *DeviceSession) getPresence() types.Presence {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.presence
+}
+
+// SendChatPresence shows composing/recording or clears it (paused) in a 1:1 chat.
+func (s *DeviceSession) SendChatPresence(ctx context.Context, to, state string) error {
+	jid := types.NewJID(to, types.DefaultUserServer)
+	if err := s.checkOptOut(ctx, jid); err != nil {
+		return err
+	}
+
+	switch state {
+	case ChatStateComposing:
+		return s.Client.SendChatPresence(ctx, jid, types.ChatPresenceComposing, types.ChatPresenceMediaText)
+	case ChatStateRecording:
+		return s.Client.SendChatPresence(ctx, jid, types.ChatPresenceComposing, types.ChatPresenceMediaAudio)
+	case ChatStatePaused:
+		return s.Client.SendChatPresence(ctx, jid, types.ChatPresencePaused, types.ChatPresenceMediaText)
+	default:
+		return fmt.Errorf("unknown chat state %q", state)
+	}
+}
+
+// SubscribePresence asks WhatsApp to deliver presence updates for a contact.
+// Updates only arrive while the device's own presence is available.
+func (s *DeviceSession) SubscribePresence(ctx context.Context, to string) error {
+	return s.Client.SubscribePresence(ctx, types.NewJID(to, types.DefaultUserServer))
+}
+
+func (s *DeviceSession) handlePresence(v *events.Presence) {
+	data := map[string]interface{}{
+		"from":      v.From.User,
+		"available": !v.Unavailable,
+	}
+	// LastSeen is zero when the contact hides it
+	if !v.LastSeen.IsZero() {
+		data["lastSeen"] = v.LastSeen
+	}
+
+	s.hub.Broadcast(s.Token, "presence.update", data)
+	s.webhook.Send("presence.update", s.Token, data)
+}
+
+func (s *DeviceSession) handleChatPresence(v *events.ChatPresence) {
+	state := ChatStatePaused
+	if v.State == types.ChatPresenceComposing {
+		state = ChatStateComposing
+		if v.Media == types.ChatPresenceMediaAudio {
+			state = ChatStateRecording
+		}
+	}
+
+	data := map[string]interface{}{
+		"chat":    v.Chat.String(),
+		"from":    v.Sender.User,
+		"isGroup": v.IsGroup,
+		"state":   state,
+	}
+
+	s.hub.Broadcast(s.Token, "chat.typing", data)
+	s.webhook.Send("chat.typing", s.Token, data)
+}
@@ -0,0 +1,43 @@
+package whatsapp
+
+import (
+	"testing"
+	"time"
+
+	"github.com/AsyrafHussin/wa-gateway-go/config"
+)
+
+func TestTypingDelay(t *testing.T) {
+	s := &DeviceSession{config: &config.Config{TypingDelay: 1000}}
+
+	tests := []struct {
+		name   string
+		typing *Typing
+		text   string
+		want   time.Duration
+	}{
+		{"default", nil, "hello", time.Second},
+		{"off", &Typing{Mode: TypingOff, Ms: 500}, "hello", 0},
+		{"fixed", &Typing{Mode: TypingFixed, Ms: 250}, "hello", 250 * time.Millisecond},
+		{"proportional", &Typing{Mode: TypingProportional, Ms: 100}, "hello", 500 * time.Millisecond},
+		{"proportional default rate", &Typing{Mode: TypingProportional}, "héllo", 250 * time.Millisecond},
+		{"capped", &Typing{Mode: TypingFixed, Ms: 600000}, "hello", maxTypingDelay},
+	}
+	for _, tt := range tests {
+		if got := s.typingDelay(tt.text, tt.typing); got != tt.want {
+			t.Errorf("%s: expected %v, got %v", tt.name, tt.want, got)
+		}
+	}
+}
+
+func TestTyping_Validate(t *testing.T) {
+	if err := (&Typing{Mode: TypingFixed, Ms: 100}).Validate(); err != nil {
+		t.Errorf("expected valid typing, got %v", err)
+	}
+	if err := (&Typing{Mode: "slow"}).Validate(); err == nil {
+		t.Error("expected error for unknown mode")
+	}
+	if err := (&Typing{Mode: TypingFixed, Ms: -1}).Validate(); err == nil {
+		t.Error("expected error for negative ms")
+	}
+}
@@ -2,6 +2,7 @@ package whatsapp
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"go.mau.fi/whatsmeow/proto/waE2E"
@@ -9,6 +10,9 @@ import (
 	"google.golang.org/protobuf/proto"
 )
 
+// ErrRecipientOptedOut is returned when sending to a contact on the device's opt-out list.
+var ErrRecipientOptedOut = errors.New("recipient has opted out")
+
 type SendResult struct {
 	ID        string    `json:"id"`
 	Timestamp time.Time `json:"timestamp"`
@@ -20,30 +24,43 @@ type ValidateResult struct {
 	JID          string `json:"jid,omitempty"`
 }
 
-func (s *DeviceSession) SendText(ctx context.Context, to, text string) (*SendResult, error) {
-	jid := types.NewJID(to, types.DefaultUserServer)
-
-	// Typing indicator
-	_ = s.Client.SendChatPresence(ctx, jid, types.ChatPresenceComposing, types.ChatPresenceMediaText)
+// SendText sends text to a phone number. typing overrides the configured
+// typing simulation; nil uses TYPING_DELAY_MS.
+func (s *DeviceSession) SendText(ctx context.Context, to, text string, typing *Typing) (*SendResult, error) {
+	return s.sendText(ctx, types.NewJID(to, types.DefaultUserServer), text, typing)
+}
 
-	// Simulate typing delay (respects context cancellation)
-	delay := time.Duration(s.config.TypingDelay) * time.Millisecond
-	select {
-	case <-ctx.Done():
-		return nil, ctx.Err()
-	case <-time.After(delay):
+// sendText delivers text to an arbitrary chat JID (used for replies where the
+// chat may be addressed by LID rather than phone number).
+func (s *DeviceSession) sendText(ctx context.Context, jid types.JID, text string, typing *Typing) (*SendResult, error) {
+	if err := s.checkOptOut(ctx, jid); err != nil {
+		return nil, err
 	}
 
-	// Stop typing
-	_ = s.Client.SendChatPresence(ctx, jid, types.ChatPresencePaused, types.ChatPresenceMediaText)
+	if delay := s.typingDelay(text, typing); delay > 0 {
+		// Typing indicator
+		_ = s.Client.SendChatPresence(ctx, jid, types.ChatPresenceComposing, types.ChatPresenceMediaText)
+
+		// Simulate typing delay (respects context cancellation)
+		select {
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		case <-time.After(delay):
+		}
+
+		// Stop typing
+		_ = s.Client.SendChatPresence(ctx, jid, types.ChatPresencePaused, types.ChatPresenceMediaText)
+	}
 
 	// Send message
 	resp, err := s.Client.SendMessage(ctx, jid, &waE2E.Message{
 		Conversation: proto.String(text),
 	})
 	if err != nil {
+		s.stats.failed.Add(1)
 		return nil, err
 	}
+	s.stats.sent.Add(1)
 
 	return &SendResult{
 		ID:        resp.ID,
@@ -51,6 +68,11 @@ func (s *DeviceSession) SendText(ctx context.Context, to, text string) (*SendRes
 	}, nil
 }
 
+// MarkRead sends read receipts for messages received in a 1:1 chat.
+func (s *DeviceSession) MarkRead(ctx context.Context, chat string, messageIDs []string) error {
+	return s.Client.MarkRead(ctx, messageIDs, time.Now(), types.NewJID(chat, types.DefaultUserServer), types.EmptyJID)
+}
+
 func (s *DeviceSession) ValidatePhone(ctx context.Context, phone string) ([]ValidateResult, error) {
 	// whatsmeow requires "+" prefix
 	results, err := s.Client.IsOnWhatsApp(ctx, []string{"+" + phone})
@@ -69,3 +91,36 @@ func (s *DeviceSession) ValidatePhone(ctx context.Context, phone string) ([]Vali
 
 	return validated, nil
 }
+
+// checkOptOut must be called by every outbound send path before anything is
+// sent to the recipient (including typing indicators). Recipients addressed
+// by LID are checked under their phone number.
+func (s *DeviceSession) checkOptOut(ctx context.Context, jid types.JID) error {
+	optedOut, err := s.OptOuts.Has(s.phoneOf(ctx, jid))
+	if err != nil {
+		return err
+	}
+	if optedOut {
+		return ErrRecipientOptedOut
+	}
+	return nil
+}
+
+// phoneOf returns the phone number of a user JID, resolving a LID through the
+// device's LID mapping. A LID that cannot be resolved is returned as is.
+func (s *DeviceSession) phoneOf(ctx context.Context, jid types.JID) string {
+	if jid.Server != types.HiddenUserServer {
+		return jid.User
+	}
+	if s.Client != nil && s.Client.Store.LIDs != nil {
+		pn, err := s.Client.Store.LIDs.GetPNForLID(ctx, jid.ToNonAD())
+		if err == nil && !pn.IsEmpty() {
+			return pn.User
+		}
+		if err != nil {
+			s.logger.Error().Err(err).Str("lid", jid.User).Msg("failed to resolve LID")
+		}
+	}
+	s.logger.Warn().Str("lid", jid.User).Msg("no phone number known for LID")
+	return jid.User
+}
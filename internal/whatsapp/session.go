@@ -6,15 +6,21 @@ import (
 	"os"
 	"path/filepath"
 	"sync"
+	"sync/atomic"
+	"time"
 
 	"github.com/rs/zerolog"
 	"go.mau.fi/whatsmeow"
 	"go.mau.fi/whatsmeow/store"
 	"go.mau.fi/whatsmeow/store/sqlstore"
+	"go.mau.fi/whatsmeow/types"
 	waLog "go.mau.fi/whatsmeow/util/log"
 
 	"github.com/AsyrafHussin/wa-gateway-go/config"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/contacts"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/optout"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/rules"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/settings"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/webhook"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/ws"
 
@@ -47,46 +53,123 @@ type DeviceSession struct {
 	Client   *whatsmeow.Client
 	device   *store.Device
 	Contacts *contacts.Store
+	Settings *settings.Store
+	Rules    *rules.Store
+	OptOuts  *optout.Store
 	status   SessionStatus
+	presence types.Presence
+	stats    sessionStats
 	mu       sync.RWMutex
 	config   *config.Config
 	hub      *ws.Hub
 	webhook  *webhook.Dispatcher
 	logger   zerolog.Logger
+
+	// optOutKeywords are inbound messages that add the sender to OptOuts
+	optOutKeywords []string
+
+	// qr and pairingCode hold the latest codes for REST retrieval
+	qr          *Pairing
+	pairingCode *Pairing
 }
 
+// deviceDirs are the data subdirectories holding one database per device.
+var deviceDirs = []string{"sessions", "contacts", "settings", "rules", "optouts"}
+
+// logoutDirs are the device databases deleted on logout. Opt-outs are kept:
+// they record the contacts' consent for the number, whoever pairs it next.
+var logoutDirs = []string{"sessions", "contacts", "settings", "rules"}
+
 func NewDeviceSession(token string, cfg *config.Config, hub *ws.Hub, dispatcher *webhook.Dispatcher, logger zerolog.Logger) (*DeviceSession, error) {
 	// Ensure directories exist
-	sessionsDir := filepath.Join(cfg.DataDir, "sessions")
-	contactsDir := filepath.Join(cfg.DataDir, "contacts")
-	if err := os.MkdirAll(sessionsDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create sessions directory: %w", err)
+	for _, dir := range deviceDirs {
+		if err := os.MkdirAll(filepath.Join(cfg.DataDir, dir), 0755); err != nil {
+			return nil, fmt.Errorf("failed to create %s directory: %w", dir, err)
+		}
 	}
-	if err := os.MkdirAll(contactsDir, 0755); err != nil {
-		return nil, fmt.Errorf("failed to create contacts directory: %w", err)
+
+	s := &DeviceSession{
+		Token:          token,
+		status:         StatusDisconnected,
+		presence:       types.PresenceAvailable,
+		optOutKeywords: optout.ParseKeywords(cfg.OptOutKeywords),
+		config:         cfg,
+		hub:            hub,
+		webhook:        dispatcher,
+		logger:         logger.With().Str("token", token).Logger(),
 	}
 
-	// Open contact store
-	contactStore, err := contacts.NewStore(filepath.Join(contactsDir, token+".db"))
-	if err != nil {
+	// Open per-device stores
+	var err error
+	if s.Contacts, err = contacts.NewStore(s.dataPath("contacts")); err != nil {
 		return nil, fmt.Errorf("failed to create contact store: %w", err)
 	}
+	if s.Settings, err = settings.NewStore(s.dataPath("settings")); err != nil {
+		s.closeStores()
+		return nil, fmt.Errorf("failed to create settings store: %w", err)
+	}
+	if s.Rules, err = rules.NewStore(s.dataPath("rules")); err != nil {
+		s.closeStores()
+		return nil, fmt.Errorf("failed to create rules store: %w", err)
+	}
+	if s.OptOuts, err = optout.NewStore(s.dataPath("optouts")); err != nil {
+		s.closeStores()
+		return nil, fmt.Errorf("failed to create opt-out store: %w", err)
+	}
 
-	return &DeviceSession{
-		Token:    token,
-		Contacts: contactStore,
-		status:   StatusDisconnected,
-		config:   cfg,
-		hub:      hub,
-		webhook:  dispatcher,
-		logger:   logger.With().Str("token", token).Logger(),
-	}, nil
+	st, err := s.Settings.Get()
+	if err != nil {
+		s.closeStores()
+		return nil, fmt.Errorf("failed to load settings: %w", err)
+	}
+	s.webhook.SetDeviceEndpoint(token, st.Webhook.URL, st.Webhook.Secret)
+
+	return s, nil
+}
+
+// dataPath returns the per-device database path inside the given data subdirectory.
+func (s *DeviceSession) dataPath(dir string) string {
+	return filepath.Join(s.config.DataDir, dir, s.Token+".db")
+}
+
+// SaveSettings persists the settings and applies the device webhook endpoint.
+func (s *DeviceSession) SaveSettings(st *settings.Settings) error {
+	if err := s.Settings.Save(st); err != nil {
+		return err
+	}
+	s.webhook.SetDeviceEndpoint(s.Token, st.Webhook.URL, st.Webhook.Secret)
+	return nil
+}
+
+// SetWebhook replaces the device's webhook endpoint in its settings.
+func (s *DeviceSession) SetWebhook(wh settings.Webhook) error {
+	st, err := s.Settings.Get()
+	if err != nil {
+		return err
+	}
+	st.Webhook = wh
+	return s.SaveSettings(st)
+}
+
+func (s *DeviceSession) closeStores() {
+	if s.Contacts != nil {
+		_ = s.Contacts.Close()
+	}
+	if s.Settings != nil {
+		_ = s.Settings.Close()
+	}
+	if s.Rules != nil {
+		_ = s.Rules.Close()
+	}
+	if s.OptOuts != nil {
+		_ = s.OptOuts.Close()
+	}
 }
 
 func (s *DeviceSession) Connect(ctx context.Context, method string) error {
 	s.setStatus(StatusConnecting)
 
-	dbPath := filepath.Join(s.config.DataDir, "sessions", s.Token+".db")
+	dbPath := s.dataPath("sessions")
 	container, err := sqlstore.New(ctx, "sqlite", "file:"+dbPath+"?_pragma=foreign_keys(1)&_pragma=journal_mode(WAL)", waLog.Noop)
 	if err != nil {
 		s.setStatus(StatusDisconnected)
@@ -98,7 +181,9 @@ func (s *DeviceSession) Connect(ctx context.Context, method string) error {
 		s.setStatus(StatusDisconnected)
 		return fmt.Errorf("failed to get device: %w", err)
 	}
+	s.mu.Lock()
 	s.device = device
+	s.mu.Unlock()
 
 	client := whatsmeow.NewClient(device, waLog.Noop)
 	client.EnableAutoReconnect = true
@@ -126,7 +211,8 @@ func (s *DeviceSession) Connect(ctx context.Context, method string) error {
 				s.setStatus(StatusDisconnected)
 				return fmt.Errorf("failed to get pairing code: %w", err)
 			}
-			s.hub.Broadcast(s.Token, "pairing-code", map[string]string{"code": code})
+			pairing := s.setPairingCode(code)
+			s.hub.Broadcast(s.Token, "pairing-code", map[string]interface{}{"code": code, "expiresAt": pairing.ExpiresAt})
 			return nil
 
 		default: // "qr"
@@ -156,13 +242,16 @@ func (s *DeviceSession) processQRChannel(qrChan <-chan whatsmeow.QRChannelItem)
 		switch evt.Event {
 		case "code":
 			s.logger.Debug().Msg("QR code received")
+			s.setQR(evt.Code, evt.Timeout)
 			s.hub.Broadcast(s.Token, "qrcode", evt.Code)
 		case "success":
 			s.logger.Info().Msg("QR pairing successful")
+			s.clearPairing()
 		case "timeout":
+			s.clearPairing()
 			s.logger.Warn().Msg("QR code timeout")
 			s.hub.BroadcastWithMessage(s.Token, "connection-error", "QR code expired")
-			s.setStatus(StatusDisconnected)
+			s.setDisconnected("QR code expired")
 		}
 	}
 }
@@ -171,12 +260,12 @@ func (s *DeviceSession) Disconnect(ctx context.Context) {
 	if s.Client != nil {
 		s.Client.Disconnect()
 	}
-	if s.Contacts != nil {
-		_ = s.Contacts.Close()
-	}
-	s.setStatus(StatusDisconnected)
+	s.closeStores()
+	s.setDisconnected("disconnected by API")
 }
 
+// Logout unlinks the device from WhatsApp and deletes its data, except for
+// its opt-outs.
 func (s *DeviceSession) Logout(ctx context.Context) {
 	if s.Client != nil {
 		if err := s.Client.Logout(ctx); err != nil {
@@ -185,11 +274,69 @@ func (s *DeviceSession) Logout(ctx context.Context) {
 	}
 	s.Disconnect(ctx)
 
-	// Remove session DB (best-effort cleanup, files may not exist)
-	dbPath := filepath.Join(s.config.DataDir, "sessions", s.Token+".db")
-	_ = os.Remove(dbPath)
-	_ = os.Remove(dbPath + "-wal")
-	_ = os.Remove(dbPath + "-shm")
+	// The token may be paired again by another tenant, so remove the
+	// tenant's per-device DBs (best-effort cleanup, files may not exist) and
+	// stop routing events to its webhook.
+	for _, dir := range logoutDirs {
+		dbPath := s.dataPath(dir)
+		_ = os.Remove(dbPath)
+		_ = os.Remove(dbPath + "-wal")
+		_ = os.Remove(dbPath + "-shm")
+	}
+	s.webhook.SetDeviceEndpoint(s.Token, "", "")
+}
+
+// sessionStats tracks connection history and message counters for Info.
+type sessionStats struct {
+	connectedSince       time.Time
+	lastDisconnectReason string
+	sent                 atomic.Int64
+	received             atomic.Int64
+	failed               atomic.Int64
+}
+
+// Info returns a snapshot of the session's status, account and counters.
+func (s *DeviceSession) Info() SessionInfo {
+	s.mu.RLock()
+	info := SessionInfo{
+		Token:                s.Token,
+		Status:               s.status.String(),
+		LastDisconnectReason: s.stats.lastDisconnectReason,
+	}
+	if !s.stats.connectedSince.IsZero() {
+		since := s.stats.connectedSince
+		info.ConnectedSince = &since
+	}
+	device := s.device
+	s.mu.RUnlock()
+
+	if device != nil && device.ID != nil {
+		info.JID = device.ID.ToNonAD().String()
+		info.PushName = device.PushName
+		info.Platform = device.Platform
+	}
+	info.Messages = MessageCounters{
+		Sent:     s.stats.sent.Load(),
+		Received: s.stats.received.Load(),
+		Failed:   s.stats.failed.Load(),
+	}
+	return info
+}
+
+func (s *DeviceSession) setConnected() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = StatusConnected
+	s.stats.connectedSince = time.Now().UTC()
+}
+
+// setDisconnected marks the session disconnected and records why.
+func (s *DeviceSession) setDisconnected(reason string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = StatusDisconnected
+	s.stats.connectedSince = time.Time{}
+	s.stats.lastDisconnectReason = reason
 }
 
 func (s *DeviceSession) GetStatus() SessionStatus {
@@ -1,14 +1,16 @@
 package ws
 
 import (
-	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"sync"
 	"sync/atomic"
 	"time"
 
 	"github.com/gofiber/contrib/websocket"
 	"github.com/rs/zerolog"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
 )
 
 const (
@@ -20,8 +22,9 @@ const (
 )
 
 type authMessage struct {
-	Type   string `json:"type"`
-	APIKey string `json:"apiKey"`
+	Type    string `json:"type"`
+	APIKey  string `json:"apiKey"`
+	LastSeq uint64 `json:"lastSeq"`
 }
 
 type authResponse struct {
@@ -30,6 +33,26 @@ type authResponse struct {
 	Message string `json:"message,omitempty"`
 }
 
+// clientMessage is a message sent by an authenticated client.
+type clientMessage struct {
+	Type    string   `json:"type"`
+	Tokens  []string `json:"tokens"`
+	LastSeq uint64   `json:"lastSeq"`
+}
+
+type subscribeResponse struct {
+	Type     string   `json:"type"`
+	Success  bool     `json:"success"`
+	Tokens   []string `json:"tokens"`
+	Replayed int      `json:"replayed,omitempty"`
+	Message  string   `json:"message,omitempty"`
+}
+
+type errorResponse struct {
+	Type    string `json:"type"`
+	Message string `json:"message"`
+}
+
 type Client struct {
 	hub           *Hub
 	conn          *websocket.Conn
@@ -37,20 +60,33 @@ type Client struct {
 	authenticated atomic.Bool
 	authDone      chan struct{}
 	authOnce      sync.Once
-	apiKey        []byte
+	authenticate  Authenticator
+	principal     Principal
 	logger        zerolog.Logger
 	writeDone     chan struct{}
+
+	// inflight bounds concurrent commands (see command.go)
+	inflight chan struct{}
+
+	// resumeSeq is the lastSeq sent with auth, used when subscribe omits one.
+	// Only touched by the ReadPump goroutine.
+	resumeSeq uint64
+
+	// tokens and closed are guarded by hub.mu
+	tokens map[string]bool
+	closed bool
 }
 
-func NewClient(hub *Hub, conn *websocket.Conn, apiKey []byte, logger zerolog.Logger) *Client {
+func NewClient(hub *Hub, conn *websocket.Conn, authenticate Authenticator, logger zerolog.Logger) *Client {
 	return &Client{
-		hub:       hub,
-		conn:      conn,
-		send:      make(chan []byte, 256),
-		authDone:  make(chan struct{}),
-		apiKey:    apiKey,
-		logger:    logger,
-		writeDone: make(chan struct{}),
+		hub:          hub,
+		conn:         conn,
+		send:         make(chan []byte, 256),
+		authDone:     make(chan struct{}),
+		authenticate: authenticate,
+		logger:       logger,
+		writeDone:    make(chan struct{}),
+		inflight:     make(chan struct{}, maxInflightCommands),
 	}
 }
 
@@ -129,11 +165,77 @@ func (c *Client) ReadPump() {
 		return
 	}
 
-	// After auth, just read and discard (keep connection alive for pong)
 	for {
-		if _, _, err := c.conn.ReadMessage(); err != nil {
+		_, msg, err := c.conn.ReadMessage()
+		if err != nil {
 			break
 		}
+		c.handleMessage(msg)
+	}
+}
+
+func (c *Client) handleMessage(msg []byte) {
+	var m clientMessage
+	if err := json.Unmarshal(msg, &m); err != nil {
+		c.reply(errorResponse{Type: "error", Message: "Invalid message format"})
+		return
+	}
+
+	switch m.Type {
+	case "subscribe", "unsubscribe":
+		if err := validateSubscription(m.Tokens); err != nil {
+			c.reply(subscribeResponse{Type: m.Type, Success: false, Tokens: []string{}, Message: err.Error()})
+			return
+		}
+		if m.Type == "subscribe" {
+			if !c.canReadEvents() {
+				c.reply(subscribeResponse{Type: m.Type, Success: false, Tokens: []string{}, Message: "API key lacks the " + apikey.ScopeDevicesRead + " scope"})
+				return
+			}
+			for _, token := range m.Tokens {
+				if token != Wildcard && !c.canUse(token) {
+					c.reply(subscribeResponse{Type: m.Type, Success: false, Tokens: []string{}, Message: "API key is not allowed to use device " + token})
+					return
+				}
+			}
+		}
+		var tokens []string
+		var replayed int
+		if m.Type == "subscribe" {
+			lastSeq := m.LastSeq
+			if lastSeq == 0 {
+				lastSeq = c.resumeSeq
+			}
+			c.resumeSeq = 0
+			tokens, replayed = c.hub.Subscribe(c, m.Tokens, lastSeq)
+		} else {
+			tokens = c.hub.Unsubscribe(c, m.Tokens)
+		}
+		c.logger.Debug().Str("remote", c.remoteAddr()).Strs("tokens", tokens).Int("replayed", replayed).Msg("WebSocket subscriptions updated")
+		c.reply(subscribeResponse{Type: m.Type, Success: true, Tokens: tokens, Replayed: replayed})
+	case "command":
+		c.handleCommand(msg)
+	default:
+		c.reply(errorResponse{Type: "error", Message: "Unknown message type"})
+	}
+}
+
+// reply queues a direct response to this client, dropping it if the buffer
+// is full or the hub has already closed the send channel.
+func (c *Client) reply(v interface{}) {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return
+	}
+
+	c.hub.mu.RLock()
+	defer c.hub.mu.RUnlock()
+	if c.closed {
+		return
+	}
+	select {
+	case c.send <- data:
+	default:
 	}
 }
 
@@ -174,14 +276,23 @@ func (c *Client) handleAuth() bool {
 		return false
 	}
 
-	if subtle.ConstantTimeCompare([]byte(authMsg.APIKey), c.apiKey) != 1 {
-		c.logger.Warn().Str("remote", c.remoteAddr()).Msg("WebSocket auth invalid API key")
+	principal, err := c.authenticate(authMsg.APIKey)
+	if err != nil {
+		if !errors.Is(err, ErrInvalidKey) {
+			c.logger.Error().Err(err).Str("remote", c.remoteAddr()).Msg("WebSocket auth failed")
+		} else {
+			c.logger.Warn().Str("remote", c.remoteAddr()).Msg("WebSocket auth invalid API key")
+		}
 		c.sendAuthError("Invalid API key")
 		c.closeAuthDone()
 		return false
 	}
 
 	// Auth successful — close authDone first to cancel timeout, then set authenticated
+	c.principal = principal
+	if c.canReadEvents() {
+		c.resumeSeq = authMsg.LastSeq
+	}
 	c.closeAuthDone()
 	c.authenticated.Store(true)
 	c.logger.Debug().Str("remote", c.remoteAddr()).Msg("WebSocket auth successful")
@@ -0,0 +1,114 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+)
+
+const (
+	// commandTimeout bounds how long a single command may run.
+	commandTimeout = 60 * time.Second
+	// maxInflightCommands bounds concurrent commands per client.
+	maxInflightCommands = 8
+)
+
+// CommandHandler executes commands received from authenticated clients.
+// Returned data is sent back as the response payload; a *CommandError
+// controls the error code, any other error is reported as COMMAND_FAILED.
+type CommandHandler interface {
+	HandleCommand(ctx context.Context, command string, params json.RawMessage) (interface{}, error)
+}
+
+// CommandError is a command failure with a machine-readable code.
+type CommandError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+}
+
+func (e *CommandError) Error() string {
+	return e.Message
+}
+
+type commandMessage struct {
+	ID      string          `json:"id"`
+	Command string          `json:"command"`
+	Params  json.RawMessage `json:"params"`
+}
+
+type commandResponse struct {
+	Type    string        `json:"type"`
+	ID      string        `json:"id"`
+	Success bool          `json:"success"`
+	Data    interface{}   `json:"data,omitempty"`
+	Error   *CommandError `json:"error,omitempty"`
+}
+
+type remoteAddrKey struct{}
+
+// RemoteAddr returns the network address of the client that sent a command.
+func RemoteAddr(ctx context.Context) string {
+	addr, _ := ctx.Value(remoteAddrKey{}).(string)
+	return addr
+}
+
+// SetCommandHandler installs the handler for client commands. Without one,
+// commands fail with COMMANDS_DISABLED.
+func (h *Hub) SetCommandHandler(handler CommandHandler) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.commands = handler
+}
+
+func (h *Hub) commandHandler() CommandHandler {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	return h.commands
+}
+
+// handleCommand runs a command off the read loop so the client can keep
+// sending; responses are correlated by the client-supplied id.
+func (c *Client) handleCommand(msg []byte) {
+	var cmd commandMessage
+	if err := json.Unmarshal(msg, &cmd); err != nil || cmd.ID == "" || cmd.Command == "" {
+		c.reply(commandResponse{Type: "response", ID: cmd.ID, Error: &CommandError{Code: "INVALID_COMMAND", Message: "Command requires id and command"}})
+		return
+	}
+
+	handler := c.hub.commandHandler()
+	if handler == nil {
+		c.reply(commandResponse{Type: "response", ID: cmd.ID, Error: &CommandError{Code: "COMMANDS_DISABLED", Message: "Commands are not enabled"}})
+		return
+	}
+
+	select {
+	case c.inflight <- struct{}{}:
+	default:
+		c.reply(commandResponse{Type: "response", ID: cmd.ID, Error: &CommandError{Code: "TOO_MANY_COMMANDS", Message: "Too many commands in flight"}})
+		return
+	}
+
+	go func() {
+		defer func() { <-c.inflight }()
+
+		ctx, cancel := context.WithTimeout(context.Background(), commandTimeout)
+		defer cancel()
+		if c.principal != nil {
+			ctx = WithPrincipal(ctx, c.principal)
+		}
+		ctx = context.WithValue(ctx, remoteAddrKey{}, c.remoteAddr())
+
+		data, err := handler.HandleCommand(ctx, cmd.Command, cmd.Params)
+		if err != nil {
+			var cmdErr *CommandError
+			if !errors.As(err, &cmdErr) {
+				cmdErr = &CommandError{Code: "COMMAND_FAILED", Message: err.Error()}
+			}
+			c.logger.Debug().Str("remote", c.remoteAddr()).Str("command", cmd.Command).Str("code", cmdErr.Code).Msg("WebSocket command failed")
+			c.reply(commandResponse{Type: "response", ID: cmd.ID, Error: cmdErr})
+			return
+		}
+		c.reply(commandResponse{Type: "response", ID: cmd.ID, Success: true, Data: data})
+	}()
+}
@@ -0,0 +1,129 @@
+package ws
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+)
+
+type fakeCommands struct {
+	block chan struct{}
+}
+
+func (f *fakeCommands) HandleCommand(ctx context.Context, command string, params json.RawMessage) (interface{}, error) {
+	switch command {
+	case "echo":
+		var p map[string]interface{}
+		_ = json.Unmarshal(params, &p)
+		return p, nil
+	case "fail":
+		return nil, &CommandError{Code: "DEVICE_NOT_FOUND", Message: "Device not found"}
+	case "boom":
+		return nil, errors.New("something broke")
+	case "block":
+		<-f.block
+		return nil, nil
+	}
+	return nil, &CommandError{Code: "UNKNOWN_COMMAND", Message: "Unknown command"}
+}
+
+func readResponse(t *testing.T, c *Client) commandResponse {
+	t.Helper()
+	select {
+	case msg := <-c.send:
+		var resp commandResponse
+		if err := json.Unmarshal(msg, &resp); err != nil {
+			t.Fatalf("failed to unmarshal response: %v", err)
+		}
+		return resp
+	case <-time.After(time.Second):
+		t.Fatal("expected a response")
+	}
+	return commandResponse{}
+}
+
+func TestClient_CommandSuccess(t *testing.T) {
+	hub := newTestHub()
+	hub.SetCommandHandler(&fakeCommands{})
+	client := mockAuthenticatedClient(hub)
+
+	client.handleMessage([]byte(`{"type":"command","id":"req-1","command":"echo","params":{"a":1}}`))
+
+	resp := readResponse(t, client)
+	if resp.Type != "response" || resp.ID != "req-1" || !resp.Success {
+		t.Fatalf("unexpected response: %+v", resp)
+	}
+	if data, _ := resp.Data.(map[string]interface{}); data["a"] != float64(1) {
+		t.Errorf("expected echoed params, got %v", resp.Data)
+	}
+}
+
+func TestClient_CommandErrors(t *testing.T) {
+	hub := newTestHub()
+	hub.SetCommandHandler(&fakeCommands{})
+	client := mockAuthenticatedClient(hub)
+
+	tests := []struct {
+		msg  string
+		code string
+	}{
+		{`{"type":"command","id":"1","command":"fail"}`, "DEVICE_NOT_FOUND"},
+		{`{"type":"command","id":"2","command":"boom"}`, "COMMAND_FAILED"},
+		{`{"type":"command","command":"echo"}`, "INVALID_COMMAND"},
+	}
+	for _, tt := range tests {
+		client.handleMessage([]byte(tt.msg))
+		resp := readResponse(t, client)
+		if resp.Success || resp.Error == nil || resp.Error.Code != tt.code {
+			t.Errorf("%s: expected error %s, got %+v", tt.msg, tt.code, resp)
+		}
+	}
+}
+
+func TestClient_CommandsDisabled(t *testing.T) {
+	hub := newTestHub()
+	client := mockAuthenticatedClient(hub)
+
+	client.handleMessage([]byte(`{"type":"command","id":"1","command":"echo","params":{}}`))
+	resp := readResponse(t, client)
+	if resp.Error == nil || resp.Error.Code != "COMMANDS_DISABLED" {
+		t.Errorf("expected COMMANDS_DISABLED, got %+v", resp)
+	}
+}
+
+func TestClient_CommandInflightLimit(t *testing.T) {
+	hub := newTestHub()
+	fake := &fakeCommands{block: make(chan struct{})}
+	defer close(fake.block)
+	hub.SetCommandHandler(fake)
+	client := mockAuthenticatedClient(hub)
+
+	for i := 0; i < maxInflightCommands; i++ {
+		client.handleMessage([]byte(`{"type":"command","id":"b","command":"block"}`))
+	}
+	client.handleMessage([]byte(`{"type":"command","id":"over","command":"echo","params":{}}`))
+
+	resp := readResponse(t, client)
+	if resp.ID != "over" || resp.Error == nil || resp.Error.Code != "TOO_MANY_COMMANDS" {
+		t.Errorf("expected TOO_MANY_COMMANDS, got %+v", resp)
+	}
+}
+
+func TestClient_UnknownMessageType(t *testing.T) {
+	hub := newTestHub()
+	client := mockAuthenticatedClient(hub)
+
+	client.handleMessage([]byte(`{"type":"bogus"}`))
+	select {
+	case msg := <-client.send:
+		var resp errorResponse
+		_ = json.Unmarshal(msg, &resp)
+		if resp.Type != "error" {
+			t.Errorf("expected error response, got %s", msg)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected an error response")
+	}
+}
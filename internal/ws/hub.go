@@ -8,30 +8,59 @@ import (
 )
 
 type Message struct {
+	Seq     uint64      `json:"seq,omitempty"`
 	Event   string      `json:"event"`
 	Token   string      `json:"token"`
 	Data    interface{} `json:"data,omitempty"`
 	Message string      `json:"message,omitempty"`
 }
 
+// outbound is a marshalled broadcast waiting for fan-out to subscribers.
+type outbound struct {
+	seq   uint64
+	token string
+	data  []byte
+}
+
 type Hub struct {
 	mu         sync.RWMutex
 	clients    map[*Client]bool
-	broadcast  chan []byte
+	broadcast  chan outbound
 	register   chan *Client
 	unregister chan *Client
 	done       chan struct{}
 	logger     zerolog.Logger
+
+	// commands executes client commands (see command.go)
+	commands CommandHandler
+
+	// subscribers indexes clients by subscribed token (see subscription.go)
+	subscribers map[string]map[*Client]bool
+
+	// history keeps recent events per token for replay (see replay.go).
+	// lastSeq is the newest sequence number assigned, fannedOut the newest
+	// one Run has delivered to clients.
+	history   map[string][]Event
+	lastSeq   uint64
+	fannedOut uint64
+
+	// listeners are in-process consumers such as SSE (see listener.go)
+	listeners map[*Listener]bool
+	closed    bool
 }
 
 func NewHub(logger zerolog.Logger) *Hub {
 	return &Hub{
 		clients:    make(map[*Client]bool),
-		broadcast:  make(chan []byte, 256),
+		broadcast:  make(chan outbound, 256),
 		register:   make(chan *Client),
 		unregister: make(chan *Client),
 		done:       make(chan struct{}),
 		logger:     logger,
+		listeners:  make(map[*Listener]bool),
+
+		subscribers: make(map[string]map[*Client]bool),
+		history:     make(map[string][]Event),
 	}
 }
 
@@ -41,9 +70,13 @@ func (h *Hub) Run() {
 		case <-h.done:
 			h.mu.Lock()
 			for client := range h.clients {
-				close(client.send)
-				delete(h.clients, client)
+				h.removeClient(client)
+			}
+			for l := range h.listeners {
+				close(l.C)
+				delete(h.listeners, l)
 			}
+			h.closed = true
 			h.mu.Unlock()
 			return
 
@@ -56,23 +89,21 @@ func (h *Hub) Run() {
 		case client := <-h.unregister:
 			h.mu.Lock()
 			if _, ok := h.clients[client]; ok {
-				delete(h.clients, client)
-				close(client.send)
+				h.removeClient(client)
 			}
 			h.mu.Unlock()
 			h.logger.Debug().Msg("WebSocket client disconnected")
 
-		case message := <-h.broadcast:
+		case out := <-h.broadcast:
 			h.mu.Lock()
-			for client := range h.clients {
-				if !client.IsAuthenticated() {
-					continue
-				}
-				select {
-				case client.send <- message:
-				default:
-					close(client.send)
-					delete(h.clients, client)
+			h.fannedOut = out.seq
+			wildcard := h.subscribers[Wildcard]
+			for client := range wildcard {
+				h.deliver(client, out.token, out.data)
+			}
+			for client := range h.subscribers[out.token] {
+				if !wildcard[client] {
+					h.deliver(client, out.token, out.data)
 				}
 			}
 			h.mu.Unlock()
@@ -80,6 +111,31 @@ func (h *Hub) Run() {
 	}
 }
 
+// deliver queues data for an authenticated client that may use the device,
+// dropping clients whose send buffer is full. Must hold h.mu.
+func (h *Hub) deliver(client *Client, token string, data []byte) {
+	if !client.IsAuthenticated() || !client.canUse(token) {
+		return
+	}
+	select {
+	case client.send <- data:
+	default:
+		h.removeClient(client)
+	}
+}
+
+// removeClient closes the client's send channel and drops it from every
+// index. Must hold h.mu.
+func (h *Hub) removeClient(client *Client) {
+	client.closed = true
+	close(client.send)
+	delete(h.clients, client)
+	for token := range client.tokens {
+		h.unindex(client, token)
+	}
+	client.tokens = nil
+}
+
 func (h *Hub) Shutdown() {
 	close(h.done)
 }
@@ -102,15 +158,26 @@ func (h *Hub) BroadcastWithMessage(token, event, message string) {
 	h.sendMessage(msg)
 }
 
+// sendMessage assigns the next sequence number, records the event for replay
+// and queues it for fan-out. Queueing under h.mu keeps the broadcast channel
+// in sequence order, which replay relies on.
 func (h *Hub) sendMessage(msg Message) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	h.lastSeq++
+	msg.Seq = h.lastSeq
+
 	bytes, err := json.Marshal(msg)
 	if err != nil {
 		h.logger.Error().Err(err).Msg("failed to marshal WebSocket message")
 		return
 	}
 
+	h.publish(msg, bytes)
+
 	select {
-	case h.broadcast <- bytes:
+	case h.broadcast <- outbound{seq: msg.Seq, token: msg.Token, data: bytes}:
 	default:
 		h.logger.Warn().Msg("WebSocket broadcast channel full, dropping message")
 	}
@@ -18,13 +18,14 @@ func newTestHub() *Hub {
 // mockClient creates a client with only a send channel (no real websocket)
 func mockClient(hub *Hub) *Client {
 	c := &Client{
-		hub:       hub,
-		conn:      nil,
-		send:      make(chan []byte, 256),
-		authDone:  make(chan struct{}),
-		apiKey:    []byte("test-key"),
-		logger:    testLogger,
-		writeDone: make(chan struct{}),
+		hub:          hub,
+		conn:         nil,
+		send:         make(chan []byte, 256),
+		authDone:     make(chan struct{}),
+		authenticate: KeyAuthenticator("test-key"),
+		logger:       testLogger,
+		writeDone:    make(chan struct{}),
+		inflight:     make(chan struct{}, maxInflightCommands),
 	}
 	return c
 }
@@ -111,6 +112,7 @@ func TestHub_Broadcast(t *testing.T) {
 
 	client := mockAuthenticatedClient(hub)
 	hub.Register(client)
+	hub.Subscribe(client, []string{Wildcard}, 0)
 	time.Sleep(50 * time.Millisecond)
 
 	hub.Broadcast("60123456789", "qrcode", "qr-data-here")
@@ -140,6 +142,7 @@ func TestHub_BroadcastWithMessage(t *testing.T) {
 
 	client := mockAuthenticatedClient(hub)
 	hub.Register(client)
+	hub.Subscribe(client, []string{Wildcard}, 0)
 	time.Sleep(50 * time.Millisecond)
 
 	hub.BroadcastWithMessage("token123", "connection-error", "Disconnected")
@@ -174,6 +177,7 @@ func TestHub_BroadcastToMultipleClients(t *testing.T) {
 	for i := range clients {
 		clients[i] = mockAuthenticatedClient(hub)
 		hub.Register(clients[i])
+		hub.Subscribe(clients[i], []string{Wildcard}, 0)
 	}
 	time.Sleep(50 * time.Millisecond)
 
@@ -293,6 +297,7 @@ func TestHub_BroadcastDataTypes(t *testing.T) {
 
 	client := mockAuthenticatedClient(hub)
 	hub.Register(client)
+	hub.Subscribe(client, []string{Wildcard}, 0)
 	time.Sleep(50 * time.Millisecond)
 
 	tests := []struct {
@@ -338,9 +343,10 @@ func TestHub_BroadcastSkipsUnauthenticated(t *testing.T) {
 	authed2 := mockAuthenticatedClient(hub)
 	unauthed := mockClient(hub)
 
-	hub.Register(authed1)
-	hub.Register(authed2)
-	hub.Register(unauthed)
+	for _, c := range []*Client{authed1, authed2, unauthed} {
+		hub.Register(c)
+		hub.Subscribe(c, []string{Wildcard}, 0)
+	}
 	time.Sleep(50 * time.Millisecond)
 
 	hub.Broadcast("token", "test-event", "data")
@@ -374,6 +380,7 @@ func TestHub_BroadcastNoneAuthenticated(t *testing.T) {
 	for i := range clients {
 		clients[i] = mockClient(hub) // all unauthenticated
 		hub.Register(clients[i])
+		hub.Subscribe(clients[i], []string{Wildcard}, 0)
 	}
 	time.Sleep(50 * time.Millisecond)
 
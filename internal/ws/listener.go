@@ -0,0 +1,83 @@
+package ws
+
+// Event is a broadcast message as delivered to in-process listeners and replay.
+type Event struct {
+	Message Message
+	Data    []byte // JSON encoding of Message
+}
+
+// Listener receives hub broadcasts outside the WebSocket client set, e.g. for
+// Server-Sent Events. C is closed when the listener falls too far behind or
+// the hub shuts down.
+type Listener struct {
+	C         chan Event
+	tokens    map[string]bool
+	principal Principal
+}
+
+func (l *Listener) wants(token string) bool {
+	if l.principal != nil && !l.principal.CanUse(token) {
+		return false
+	}
+	return len(l.tokens) == 0 || l.tokens[token]
+}
+
+// Listen registers a listener for the given tokens (all tokens if empty)
+// that p can use (any if nil) and returns the buffered events after lastSeq,
+// so replay and live delivery neither overlap nor leave a gap.
+func (h *Hub) Listen(p Principal, tokens []string, lastSeq uint64) (*Listener, []Event) {
+	l := &Listener{C: make(chan Event, 64), tokens: make(map[string]bool, len(tokens)), principal: p}
+	for _, t := range tokens {
+		l.tokens[t] = true
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	if h.closed {
+		close(l.C)
+		return l, nil
+	}
+
+	if len(tokens) == 0 {
+		tokens = []string{Wildcard}
+	}
+	var missed []Event
+	for _, evt := range h.missed(tokens, lastSeq, h.lastSeq) {
+		if l.wants(evt.Message.Token) {
+			missed = append(missed, evt)
+		}
+	}
+	h.listeners[l] = true
+	return l, missed
+}
+
+// Unlisten removes a listener. It is safe to call after C was closed.
+func (h *Hub) Unlisten(l *Listener) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if _, ok := h.listeners[l]; ok {
+		delete(h.listeners, l)
+		close(l.C)
+	}
+}
+
+// publish records msg for replay and hands it to listeners. Must hold h.mu.
+func (h *Hub) publish(msg Message, data []byte) {
+	evt := Event{Message: msg, Data: data}
+	h.record(evt)
+
+	for l := range h.listeners {
+		if !l.wants(msg.Token) {
+			continue
+		}
+		select {
+		case l.C <- evt:
+		default:
+			// Too slow: drop the listener so it reconnects and replays
+			h.logger.Warn().Msg("event listener too slow, disconnecting")
+			delete(h.listeners, l)
+			close(l.C)
+		}
+	}
+}
@@ -0,0 +1,104 @@
+package ws
+
+import (
+	"testing"
+	"time"
+)
+
+func TestHub_ListenFiltersByToken(t *testing.T) {
+	hub := newTestHub()
+
+	l, missed := hub.Listen(nil, []string{"60123456789"}, 0)
+	defer hub.Unlisten(l)
+	if len(missed) != 0 {
+		t.Fatalf("expected no replay without lastID, got %d", len(missed))
+	}
+
+	hub.Broadcast("60198765432", "qrcode", "other")
+	hub.Broadcast("60123456789", "qrcode", "mine")
+
+	select {
+	case evt := <-l.C:
+		if evt.Message.Token != "60123456789" || evt.Message.Data != "mine" {
+			t.Errorf("unexpected event: %+v", evt.Message)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("expected event for subscribed token")
+	}
+
+	select {
+	case evt := <-l.C:
+		t.Errorf("unexpected extra event: %+v", evt.Message)
+	default:
+	}
+}
+
+func TestHub_ListenReplaysAfterLastID(t *testing.T) {
+	hub := newTestHub()
+
+	hub.Broadcast("60123456789", "qrcode", "one")
+	hub.Broadcast("60123456789", "qrcode", "two")
+	hub.Broadcast("60123456789", "connection-success", nil)
+
+	l, missed := hub.Listen(nil, nil, 1)
+	defer hub.Unlisten(l)
+
+	if len(missed) != 2 {
+		t.Fatalf("expected 2 replayed events, got %d", len(missed))
+	}
+	if missed[0].Message.Seq != 2 || missed[1].Message.Event != "connection-success" {
+		t.Errorf("unexpected replay: %+v", missed)
+	}
+}
+
+func TestHub_ReplayBufferBounded(t *testing.T) {
+	hub := newTestHub()
+	for i := 0; i < replaySize+10; i++ {
+		hub.Broadcast("60123456789", "qrcode", i)
+	}
+
+	l, missed := hub.Listen(nil, nil, 1)
+	defer hub.Unlisten(l)
+	if len(missed) != replaySize {
+		t.Errorf("expected %d replayed events, got %d", replaySize, len(missed))
+	}
+}
+
+func TestHub_ShutdownClosesListeners(t *testing.T) {
+	hub := newTestHub()
+	go hub.Run()
+
+	l, _ := hub.Listen(nil, nil, 0)
+	hub.Shutdown()
+
+	select {
+	case _, ok := <-l.C:
+		if ok {
+			t.Error("expected listener channel to be closed")
+		}
+	case <-time.After(time.Second):
+		t.Fatal("listener not closed on shutdown")
+	}
+	hub.Unlisten(l)
+}
+
+func TestHub_ListenFiltersByPrincipal(t *testing.T) {
+	hub := newTestHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	l, _ := hub.Listen(onlyDevice("60123456789"), nil, 0)
+	defer hub.Unlisten(l)
+
+	hub.Broadcast("60198765432", "qrcode", "other")
+	hub.Broadcast("60123456789", "qrcode", "own")
+
+	select {
+	case evt := <-l.C:
+		if evt.Message.Token != "60123456789" {
+			t.Errorf("expected only the allowed device's events, got %q", evt.Message.Token)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timed out waiting for event")
+	}
+}
@@ -0,0 +1,66 @@
+package ws
+
+import (
+	"context"
+	"crypto/subtle"
+	"errors"
+
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+)
+
+// ErrInvalidKey is returned by an Authenticator for an unknown API key.
+var ErrInvalidKey = errors.New("invalid API key")
+
+// Principal is what a client authenticated as. Clients and listeners only
+// receive events for, and may only run commands on, devices it can use.
+type Principal interface {
+	CanUse(token string) bool
+}
+
+// Authenticator resolves the API key from a client's auth message.
+type Authenticator func(apiKey string) (Principal, error)
+
+// allDevices is the principal of a client that may use every device.
+type allDevices struct{}
+
+func (allDevices) CanUse(string) bool { return true }
+
+// KeyAuthenticator accepts a single API key with access to every device.
+func KeyAuthenticator(apiKey string) Authenticator {
+	key := []byte(apiKey)
+	return func(got string) (Principal, error) {
+		if subtle.ConstantTimeCompare([]byte(got), key) != 1 {
+			return nil, ErrInvalidKey
+		}
+		return allDevices{}, nil
+	}
+}
+
+type principalKey struct{}
+
+// WithPrincipal returns a context carrying the principal of the client that
+// sent a command.
+func WithPrincipal(ctx context.Context, p Principal) context.Context {
+	return context.WithValue(ctx, principalKey{}, p)
+}
+
+// PrincipalFrom returns the principal of the client that sent a command.
+func PrincipalFrom(ctx context.Context) Principal {
+	if p, ok := ctx.Value(principalKey{}).(Principal); ok {
+		return p
+	}
+	return allDevices{}
+}
+
+// canUse reports whether the client may use the device. The principal is
+// set before the client is marked authenticated and never changes.
+func (c *Client) canUse(token string) bool {
+	return c.principal == nil || c.principal.CanUse(token)
+}
+
+// canReadEvents reports whether the client may subscribe to device events.
+// Like GET /events, keys with scopes need devices:read.
+func (c *Client) canReadEvents() bool {
+	p, ok := c.principal.(interface{ HasScope(string) bool })
+	return !ok || p.HasScope(apikey.ScopeDevicesRead)
+}
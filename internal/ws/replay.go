@@ -0,0 +1,57 @@
+package ws
+
+import "sort"
+
+// replaySize bounds how many recent events are kept per token.
+const replaySize = 100
+
+// record appends evt to its token's ring buffer. Must hold h.mu.
+func (h *Hub) record(evt Event) {
+	token := evt.Message.Token
+	events := append(h.history[token], evt)
+	if len(events) > replaySize {
+		// Copy so the dropped events' backing array can be freed
+		events = append([]Event(nil), events[len(events)-replaySize:]...)
+	}
+	h.history[token] = events
+}
+
+// missed returns buffered events for tokens (Wildcard for all) with
+// lastSeq < seq <= upTo, in sequence order. A lastSeq of 0 means no replay;
+// a lastSeq ahead of the hub (e.g. after a server restart) replays
+// everything buffered. Must hold h.mu.
+func (h *Hub) missed(tokens []string, lastSeq, upTo uint64) []Event {
+	if lastSeq == 0 {
+		return nil
+	}
+	if lastSeq > h.lastSeq {
+		lastSeq = 0
+	}
+
+	seen := make(map[string]bool)
+	var events []Event
+	collect := func(token string) {
+		if seen[token] {
+			return
+		}
+		seen[token] = true
+		for _, evt := range h.history[token] {
+			if evt.Message.Seq > lastSeq && evt.Message.Seq <= upTo {
+				events = append(events, evt)
+			}
+		}
+	}
+
+	for _, token := range tokens {
+		if token != Wildcard {
+			collect(token)
+			continue
+		}
+		for t := range h.history {
+			collect(t)
+		}
+	}
+
+	sort.Slice(events, func(i, j int) bool { return events[i].Message.Seq < events[j].Message.Seq })
+	return events
+}
@@ -0,0 +1,125 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func drain(t *testing.T, c *Client) []Message {
+	t.Helper()
+	var msgs []Message
+	for {
+		select {
+		case data := <-c.send:
+			var m Message
+			if err := json.Unmarshal(data, &m); err != nil {
+				t.Fatalf("failed to unmarshal: %v", err)
+			}
+			msgs = append(msgs, m)
+		default:
+			return msgs
+		}
+	}
+}
+
+func TestHub_SequenceNumbers(t *testing.T) {
+	hub := newTestHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	client := mockAuthenticatedClient(hub)
+	hub.Register(client)
+	hub.Subscribe(client, []string{Wildcard}, 0)
+	time.Sleep(50 * time.Millisecond)
+
+	hub.Broadcast("60123456789", "qrcode", "a")
+	hub.Broadcast("60198765432", "qrcode", "b")
+	time.Sleep(50 * time.Millisecond)
+
+	msgs := drain(t, client)
+	if len(msgs) != 2 || msgs[0].Seq != 1 || msgs[1].Seq != 2 {
+		t.Fatalf("expected seq 1, 2, got %+v", msgs)
+	}
+}
+
+func TestHub_SubscribeReplaysMissedEvents(t *testing.T) {
+	hub := newTestHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	hub.Broadcast("60123456789", "qrcode", "one")           // seq 1
+	hub.Broadcast("60198765432", "qrcode", "other")         // seq 2
+	hub.Broadcast("60123456789", "qrcode", "two")           // seq 3
+	hub.Broadcast("60123456789", "connection-success", nil) // seq 4
+	time.Sleep(50 * time.Millisecond)
+
+	client := mockAuthenticatedClient(hub)
+	hub.Register(client)
+	_, replayed := hub.Subscribe(client, []string{"60123456789"}, 1)
+	if replayed != 2 {
+		t.Fatalf("expected 2 replayed events, got %d", replayed)
+	}
+
+	hub.Broadcast("60123456789", "connection-error", nil) // seq 5, live
+	time.Sleep(50 * time.Millisecond)
+
+	msgs := drain(t, client)
+	var seqs []uint64
+	for _, m := range msgs {
+		seqs = append(seqs, m.Seq)
+	}
+	if len(seqs) != 3 || seqs[0] != 3 || seqs[1] != 4 || seqs[2] != 5 {
+		t.Errorf("expected seq 3, 4, 5 without gaps or duplicates, got %v", seqs)
+	}
+}
+
+func TestHub_SubscribeWithoutLastSeqDoesNotReplay(t *testing.T) {
+	hub := newTestHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	hub.Broadcast("60123456789", "qrcode", "old")
+	time.Sleep(50 * time.Millisecond)
+
+	client := mockAuthenticatedClient(hub)
+	hub.Register(client)
+	if _, replayed := hub.Subscribe(client, []string{Wildcard}, 0); replayed != 0 {
+		t.Errorf("expected no replay, got %d", replayed)
+	}
+}
+
+func TestHub_ReplayAfterRestartSendsAllBuffered(t *testing.T) {
+	hub := newTestHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	hub.Broadcast("60123456789", "qrcode", "a")
+	hub.Broadcast("60123456789", "qrcode", "b")
+	time.Sleep(50 * time.Millisecond)
+
+	client := mockAuthenticatedClient(hub)
+	hub.Register(client)
+
+	// lastSeq from before a restart is ahead of the new hub's counter
+	if _, replayed := hub.Subscribe(client, []string{"60123456789"}, 500); replayed != 2 {
+		t.Errorf("expected all 2 buffered events, got %d", replayed)
+	}
+}
+
+func TestHub_HistoryBoundedPerToken(t *testing.T) {
+	hub := newTestHub()
+	for i := 0; i < replaySize+10; i++ {
+		hub.Broadcast("60123456789", "qrcode", i)
+	}
+	hub.Broadcast("60198765432", "qrcode", "other")
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	if n := len(hub.history["60123456789"]); n != replaySize {
+		t.Errorf("expected %d buffered events, got %d", replaySize, n)
+	}
+	if n := len(hub.history["60198765432"]); n != 1 {
+		t.Errorf("expected other token's history to be kept, got %d", n)
+	}
+}
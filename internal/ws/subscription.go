@@ -0,0 +1,100 @@
+package ws
+
+import (
+	"errors"
+	"sort"
+
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/validator"
+)
+
+// Wildcard subscribes a client to events for every device.
+const Wildcard = "*"
+
+var errInvalidSubscription = errors.New(`tokens must be device tokens (7-15 digits) or "*"`)
+
+func validateSubscription(tokens []string) error {
+	if len(tokens) == 0 {
+		return errInvalidSubscription
+	}
+	for _, token := range tokens {
+		if token == Wildcard {
+			continue
+		}
+		if err := validator.ValidateToken(token); err != nil {
+			return errInvalidSubscription
+		}
+	}
+	return nil
+}
+
+// Subscribe routes broadcasts for the given tokens (or Wildcard) to the
+// client and returns its full subscription list. With lastSeq > 0, buffered
+// events for those tokens after lastSeq are queued first; replayed reports
+// how many.
+func (h *Hub) Subscribe(client *Client, tokens []string, lastSeq uint64) (subscribed []string, replayed int) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	// Events past fannedOut are still queued for Run, which will deliver
+	// them once the client is indexed below
+replay:
+	for _, evt := range h.missed(tokens, lastSeq, h.fannedOut) {
+		if client.closed || !client.IsAuthenticated() {
+			break
+		}
+		if !client.canUse(evt.Message.Token) {
+			continue
+		}
+		select {
+		case client.send <- evt.Data:
+			replayed++
+		default:
+			h.logger.Warn().Int("replayed", replayed).Msg("WebSocket replay truncated, client buffer full")
+			break replay
+		}
+	}
+
+	if client.tokens == nil {
+		client.tokens = make(map[string]bool)
+	}
+	for _, token := range tokens {
+		client.tokens[token] = true
+		if h.subscribers[token] == nil {
+			h.subscribers[token] = make(map[*Client]bool)
+		}
+		h.subscribers[token][client] = true
+	}
+	return client.subscriptions(), replayed
+}
+
+// Unsubscribe stops routing the given tokens to the client and returns its
+// remaining subscriptions.
+func (h *Hub) Unsubscribe(client *Client, tokens []string) []string {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, token := range tokens {
+		delete(client.tokens, token)
+		h.unindex(client, token)
+	}
+	return client.subscriptions()
+}
+
+// unindex removes client from a token's subscriber set. Must hold h.mu.
+func (h *Hub) unindex(client *Client, token string) {
+	set := h.subscribers[token]
+	delete(set, client)
+	if len(set) == 0 {
+		delete(h.subscribers, token)
+	}
+}
+
+// subscriptions lists the client's tokens in sorted order. Callers must hold hub.mu.
+func (c *Client) subscriptions() []string {
+	tokens := make([]string, 0, len(c.tokens))
+	for token := range c.tokens {
+		tokens = append(tokens, token)
+	}
+	sort.Strings(tokens)
+	return tokens
+}
@@ -0,0 +1,159 @@
+package ws
+
+import (
+	"encoding/json"
+	"testing"
+	"time"
+)
+
+func TestHub_BroadcastOnlyToSubscribedToken(t *testing.T) {
+	hub := newTestHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	deviceA := mockAuthenticatedClient(hub)
+	deviceB := mockAuthenticatedClient(hub)
+	unsubscribed := mockAuthenticatedClient(hub)
+	for _, c := range []*Client{deviceA, deviceB, unsubscribed} {
+		hub.Register(c)
+	}
+	hub.Subscribe(deviceA, []string{"60123456789"}, 0)
+	hub.Subscribe(deviceB, []string{"60198765432"}, 0)
+	time.Sleep(50 * time.Millisecond)
+
+	hub.Broadcast("60123456789", "qrcode", "qr-for-a")
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+	case msg := <-deviceA.send:
+		var m Message
+		_ = json.Unmarshal(msg, &m)
+		if m.Token != "60123456789" {
+			t.Errorf("expected token 60123456789, got %q", m.Token)
+		}
+	default:
+		t.Error("subscribed client should receive its device's event")
+	}
+
+	for name, c := range map[string]*Client{"other device": deviceB, "unsubscribed": unsubscribed} {
+		select {
+		case <-c.send:
+			t.Errorf("%s client should not receive the event", name)
+		default:
+		}
+	}
+}
+
+func TestHub_WildcardAndTokenDeliveredOnce(t *testing.T) {
+	hub := newTestHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	client := mockAuthenticatedClient(hub)
+	hub.Register(client)
+	hub.Subscribe(client, []string{Wildcard, "60123456789"}, 0)
+	time.Sleep(50 * time.Millisecond)
+
+	hub.Broadcast("60123456789", "qrcode", "data")
+	time.Sleep(50 * time.Millisecond)
+
+	if n := len(client.send); n != 1 {
+		t.Errorf("expected exactly 1 message, got %d", n)
+	}
+}
+
+func TestHub_Unsubscribe(t *testing.T) {
+	hub := newTestHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	client := mockAuthenticatedClient(hub)
+	hub.Register(client)
+	hub.Subscribe(client, []string{"60123456789", "60198765432"}, 0)
+
+	remaining := hub.Unsubscribe(client, []string{"60123456789"})
+	if len(remaining) != 1 || remaining[0] != "60198765432" {
+		t.Fatalf("unexpected remaining subscriptions: %v", remaining)
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	hub.Broadcast("60123456789", "qrcode", "data")
+	time.Sleep(50 * time.Millisecond)
+
+	select {
+	case <-client.send:
+		t.Error("unsubscribed token should not be delivered")
+	default:
+	}
+}
+
+func TestHub_UnregisterRemovesSubscriptions(t *testing.T) {
+	hub := newTestHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	client := mockAuthenticatedClient(hub)
+	hub.Register(client)
+	hub.Subscribe(client, []string{"60123456789"}, 0)
+	time.Sleep(50 * time.Millisecond)
+
+	hub.Unregister(client)
+	time.Sleep(50 * time.Millisecond)
+
+	hub.mu.RLock()
+	defer hub.mu.RUnlock()
+	if len(hub.subscribers) != 0 {
+		t.Errorf("expected empty subscriber index, got %v", hub.subscribers)
+	}
+}
+
+func TestValidateSubscription(t *testing.T) {
+	valid := [][]string{{"*"}, {"60123456789"}, {"60123456789", "*"}}
+	for _, tokens := range valid {
+		if err := validateSubscription(tokens); err != nil {
+			t.Errorf("%v: expected valid, got %v", tokens, err)
+		}
+	}
+
+	invalid := [][]string{nil, {}, {"abc"}, {"60123456789", ""}}
+	for _, tokens := range invalid {
+		if err := validateSubscription(tokens); err == nil {
+			t.Errorf("%v: expected error", tokens)
+		}
+	}
+}
+
+// onlyDevice is a principal limited to one device
+type onlyDevice string
+
+func (d onlyDevice) CanUse(token string) bool { return token == string(d) }
+
+func TestHub_PrincipalLimitsWildcardAndReplay(t *testing.T) {
+	hub := newTestHub()
+	go hub.Run()
+	defer hub.Shutdown()
+
+	hub.Broadcast("60198765432", "qrcode", "before-other")
+	hub.Broadcast("60123456789", "qrcode", "before-own")
+	time.Sleep(50 * time.Millisecond)
+
+	client := mockAuthenticatedClient(hub)
+	client.principal = onlyDevice("60123456789")
+	hub.Register(client)
+	hub.Subscribe(client, []string{Wildcard}, 1)
+	time.Sleep(50 * time.Millisecond)
+
+	hub.Broadcast("60198765432", "qrcode", "live-other")
+	hub.Broadcast("60123456789", "qrcode", "live-own")
+	time.Sleep(50 * time.Millisecond)
+
+	var got []string
+	for len(client.send) > 0 {
+		var m Message
+		_ = json.Unmarshal(<-client.send, &m)
+		got = append(got, m.Data.(string))
+	}
+	if len(got) != 2 || got[0] != "before-own" || got[1] != "live-own" {
+		t.Errorf("expected only the allowed device's events, got %v", got)
+	}
+}
@@ -5,17 +5,22 @@ import (
 	"fmt"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
 	"github.com/rs/zerolog"
 
 	"github.com/AsyrafHussin/wa-gateway-go/config"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/apikey"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/audit"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/cache"
+	"github.com/AsyrafHussin/wa-gateway-go/internal/jwtauth"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/server"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/webhook"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/whatsapp"
 	"github.com/AsyrafHussin/wa-gateway-go/internal/ws"
+	"github.com/AsyrafHussin/wa-gateway-go/pkg/webhooksig"
 )
 
 func main() {
@@ -44,7 +49,67 @@ func main() {
 	hub := ws.NewHub(logger)
 	go hub.Run()
 
-	dispatcher := webhook.NewDispatcher(cfg.WebhookURL, cfg.WebhookSecret, cfg.WebhookTimeout, logger)
+	if err := os.MkdirAll(cfg.DataDir, 0755); err != nil {
+		logger.Fatal().Err(err).Msg("failed to create data directory")
+	}
+	auditLog, err := audit.NewStore(filepath.Join(cfg.DataDir, "audit.db"))
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to open audit log")
+	}
+	// Logouts from the phone reach the audit log through the dispatcher
+	sinks := []webhook.Sink{audit.NewSink(auditLog)}
+	if cfg.HasSink("nats") {
+		subjects, err := webhook.ParseSubjects(cfg.NATSSubject, cfg.NATSSubjects)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("invalid NATS subjects")
+		}
+		sink, err := webhook.NewNATSSink(cfg.NATSURL, subjects, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to create NATS sink")
+		}
+		sinks = append(sinks, sink)
+	}
+	dispatcher, err := webhook.NewDispatcher(webhook.Config{
+		URL:          cfg.WebhookURL,
+		Secret:       cfg.WebhookSecret,
+		Secrets:      webhooksig.ParseSecrets(cfg.WebhookSecrets),
+		Format:       cfg.WebhookFormat,
+		Source:       cfg.WebhookSource,
+		Timeout:      time.Duration(cfg.WebhookTimeout) * time.Millisecond,
+		MaxAttempts:  cfg.WebhookMaxAttempts,
+		RetryBase:    time.Duration(cfg.WebhookRetryBase) * time.Millisecond,
+		RetryMax:     time.Duration(cfg.WebhookRetryMax) * time.Millisecond,
+		Ordering:     cfg.WebhookOrdering,
+		Concurrency:  cfg.WebhookConcurrency,
+		BatchSize:    cfg.WebhookBatchSize,
+		BatchWindow:  time.Duration(cfg.WebhookBatchWindow) * time.Millisecond,
+		LogRetention: time.Duration(cfg.WebhookLogHours) * time.Hour,
+		DBPath:       filepath.Join(cfg.DataDir, "webhooks.db"),
+		Sinks:        sinks,
+		DisableHTTP:  !cfg.HasSink("http"),
+	}, logger)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to create webhook dispatcher")
+	}
+	dispatcher.Start()
+	keys, err := apikey.NewStore(filepath.Join(cfg.DataDir, "keys.db"))
+	if err != nil {
+		logger.Fatal().Err(err).Msg("failed to open API key store")
+	}
+	var verifier *jwtauth.Verifier
+	if cfg.JWTEnabled() {
+		verifier, err = jwtauth.New(jwtauth.Config{
+			JWKSFile:    cfg.JWTJWKSFile,
+			Issuer:      cfg.JWTIssuer,
+			Audience:    cfg.JWTAudience,
+			ScopeClaim:  cfg.JWTScopeClaim,
+			TokensClaim: cfg.JWTTokensClaim,
+			TenantClaim: cfg.JWTTenantClaim,
+		}, logger)
+		if err != nil {
+			logger.Fatal().Err(err).Msg("failed to set up JWT authentication")
+		}
+	}
 	phoneCache := cache.NewPhoneCache(cfg.CacheTTL)
 	manager := whatsapp.NewDeviceManager(cfg, hub, dispatcher, logger)
 
@@ -53,7 +118,7 @@ func main() {
 	manager.AutoReconnect(ctx)
 
 	// Create and start server
-	srv := server.New(cfg, manager, hub, dispatcher, phoneCache, logger)
+	srv := server.New(cfg, manager, hub, dispatcher, keys, verifier, auditLog, phoneCache, logger)
 
 	// Graceful shutdown
 	quit := make(chan os.Signal, 1)
@@ -78,6 +143,15 @@ func main() {
 	if err := srv.App.Shutdown(); err != nil {
 		logger.Error().Err(err).Msg("server shutdown error")
 	}
+	if err := dispatcher.Close(); err != nil {
+		logger.Error().Err(err).Msg("webhook dispatcher shutdown error")
+	}
+	if err := keys.Close(); err != nil {
+		logger.Error().Err(err).Msg("API key store shutdown error")
+	}
+	if err := auditLog.Close(); err != nil {
+		logger.Error().Err(err).Msg("audit log shutdown error")
+	}
 
 	logger.Info().Msg("goodbye")
 }
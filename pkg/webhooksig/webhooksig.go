@@ -0,0 +1,114 @@
+// Package webhooksig signs and verifies wa-gateway-go webhook requests.
+//
+// Each request carries a delivery ID, a Unix timestamp and one or more
+// versioned signatures:
+//
+//	X-Webhook-Id: 0b6c6f0e-3f0c-4a51-9d4b-0a4c1f7e2d11
+//	X-Webhook-Timestamp: 1771324200
+//	X-Webhook-Signatures: v1=5f2b...,v1=9a0c...
+//
+// A v1 signature is the hex HMAC-SHA256 of "<timestamp>.<body>". There is one
+// signature per active secret, so receivers keep verifying while secrets are
+// rotated. Receivers should reject stale timestamps to block replays and may
+// use the delivery ID to drop duplicates.
+//
+// Until the next release, requests also carry the deprecated
+// X-Webhook-Signature header: the bare HMAC of the body with the first
+// secret, as sent by earlier versions.
+package webhooksig
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	HeaderID        = "X-Webhook-Id"
+	HeaderTimestamp = "X-Webhook-Timestamp"
+	HeaderSignature = "X-Webhook-Signatures"
+
+	// Version prefixes each signature in the signature header
+	Version = "v1"
+
+	// DefaultTolerance is the maximum accepted clock difference
+	DefaultTolerance = 5 * time.Minute
+)
+
+var (
+	ErrMissingHeaders   = errors.New("missing webhook timestamp or signature header")
+	ErrInvalidTimestamp = errors.New("invalid webhook timestamp")
+	ErrTimestampExpired = errors.New("webhook timestamp outside tolerance")
+	ErrNoSignature      = errors.New("no matching webhook signature")
+)
+
+// Sign returns the hex v1 signature of body at timestamp.
+func Sign(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// SignatureHeader returns the X-Webhook-Signatures value with one v1
+// signature per secret.
+func SignatureHeader(secrets []string, timestamp int64, body []byte) string {
+	sigs := make([]string, 0, len(secrets))
+	for _, secret := range secrets {
+		sigs = append(sigs, Version+"="+Sign(secret, timestamp, body))
+	}
+	return strings.Join(sigs, ",")
+}
+
+// ParseSecrets splits a comma-separated secret list, dropping empty entries.
+func ParseSecrets(list string) []string {
+	var secrets []string
+	for _, s := range strings.Split(list, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			secrets = append(secrets, s)
+		}
+	}
+	return secrets
+}
+
+// Verify checks that the request was signed with one of secrets within
+// tolerance of now. A tolerance of 0 uses DefaultTolerance.
+func Verify(header http.Header, body []byte, secrets []string, tolerance time.Duration) error {
+	return verifyAt(header, body, secrets, tolerance, time.Now())
+}
+
+func verifyAt(header http.Header, body []byte, secrets []string, tolerance time.Duration, now time.Time) error {
+	tsHeader := header.Get(HeaderTimestamp)
+	sigHeader := header.Get(HeaderSignature)
+	if tsHeader == "" || sigHeader == "" {
+		return ErrMissingHeaders
+	}
+
+	timestamp, err := strconv.ParseInt(tsHeader, 10, 64)
+	if err != nil {
+		return ErrInvalidTimestamp
+	}
+	if tolerance <= 0 {
+		tolerance = DefaultTolerance
+	}
+	if diff := now.Sub(time.Unix(timestamp, 0)); diff > tolerance || diff < -tolerance {
+		return ErrTimestampExpired
+	}
+
+	for _, secret := range secrets {
+		expected := []byte(Sign(secret, timestamp, body))
+		for _, part := range strings.Split(sigHeader, ",") {
+			version, sig, ok := strings.Cut(strings.TrimSpace(part), "=")
+			if ok && version == Version && hmac.Equal([]byte(sig), expected) {
+				return nil
+			}
+		}
+	}
+	return ErrNoSignature
+}
@@ -0,0 +1,81 @@
+package webhooksig
+
+import (
+	"net/http"
+	"strconv"
+	"testing"
+	"time"
+)
+
+func signedHeader(secrets []string, timestamp int64, body []byte) http.Header {
+	h := http.Header{}
+	h.Set(HeaderTimestamp, strconv.FormatInt(timestamp, 10))
+	h.Set(HeaderSignature, SignatureHeader(secrets, timestamp, body))
+	return h
+}
+
+func TestSign(t *testing.T) {
+	// echo -n '1700000000.{"event":"device.connected"}' | openssl dgst -sha256 -hmac secret
+	got := Sign("secret", 1700000000, []byte(`{"event":"device.connected"}`))
+	want := "e0c84df9b808c3af93fa5ca0236c211aa4bf8c84a2203695b7254a5bce01dc0a"
+	if got != want {
+		t.Fatalf("Sign() = %q, want %q", got, want)
+	}
+	if got == Sign("secret", 1700000001, []byte(`{"event":"device.connected"}`)) {
+		t.Fatal("expected timestamp to be part of the signature")
+	}
+}
+
+func TestVerify(t *testing.T) {
+	body := []byte(`{"event":"device.connected"}`)
+	now := time.Unix(1700000000, 0)
+	ts := now.Unix()
+
+	tests := []struct {
+		name    string
+		header  http.Header
+		body    []byte
+		secrets []string
+		want    error
+	}{
+		{"valid", signedHeader([]string{"secret"}, ts, body), body, []string{"secret"}, nil},
+		{"rotated sender", signedHeader([]string{"new", "old"}, ts, body), body, []string{"old"}, nil},
+		{"rotated receiver", signedHeader([]string{"old"}, ts, body), body, []string{"new", "old"}, nil},
+		{"wrong secret", signedHeader([]string{"secret"}, ts, body), body, []string{"other"}, ErrNoSignature},
+		{"tampered body", signedHeader([]string{"secret"}, ts, body), []byte(`{}`), []string{"secret"}, ErrNoSignature},
+		{"stale", signedHeader([]string{"secret"}, ts-600, body), body, []string{"secret"}, ErrTimestampExpired},
+		{"future", signedHeader([]string{"secret"}, ts+600, body), body, []string{"secret"}, ErrTimestampExpired},
+		{"missing", http.Header{}, body, []string{"secret"}, ErrMissingHeaders},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := verifyAt(tt.header, tt.body, tt.secrets, 0, now); err != tt.want {
+				t.Errorf("verify() = %v, want %v", err, tt.want)
+			}
+		})
+	}
+
+	bad := signedHeader([]string{"secret"}, ts, body)
+	bad.Set(HeaderTimestamp, "yesterday")
+	if err := verifyAt(bad, body, []string{"secret"}, 0, now); err != ErrInvalidTimestamp {
+		t.Errorf("expected ErrInvalidTimestamp, got %v", err)
+	}
+
+	// Replaying a captured signature with a fresh timestamp fails
+	replayed := signedHeader([]string{"secret"}, ts-600, body)
+	replayed.Set(HeaderTimestamp, strconv.FormatInt(ts, 10))
+	if err := verifyAt(replayed, body, []string{"secret"}, 0, now); err != ErrNoSignature {
+		t.Errorf("expected ErrNoSignature for re-stamped request, got %v", err)
+	}
+}
+
+func TestParseSecrets(t *testing.T) {
+	got := ParseSecrets(" new , ,old,")
+	if len(got) != 2 || got[0] != "new" || got[1] != "old" {
+		t.Fatalf("unexpected secrets: %q", got)
+	}
+	if ParseSecrets("") != nil {
+		t.Fatal("expected no secrets for empty list")
+	}
+}